@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -15,51 +16,121 @@ type Agent struct {
 	SystemPrompt string
 	Config       map[string]string
 	Warnings     []string
-}
 
-var knownKeys = map[string]struct{}{
-	"api-key":           {},
-	"endpoint":          {},
-	"max-tokens":        {},
-	"max_tokens":        {},
-	"model":             {},
-	"org":               {},
-	"organization":      {},
-	"provider":          {},
-	"temperature":       {},
-	"top-p":             {},
-	"top_p":             {},
-	"tool-choice":       {},
-	"tool_choice":       {},
-	"max-output-tokens": {},
-	"max_output_tokens": {},
+	// Name and Description are the listing metadata from the name and
+	// description frontmatter keys.  Discover falls back to the file name
+	// when name is absent.
+	Name        string
+	Description string
+
+	// Path is the file the agent was loaded from; empty for Parse.
+	Path string
+
+	// Dirs lists the absolute directories of the agent file and of every
+	// parent in its extends chain, nearest first; empty for Parse.  Each
+	// file's includes resolve relative to its own directory.
+	Dirs []string
+
+	// Extends is the extends frontmatter value: a parent agent path
+	// (relative to the agent file) or name.  Load resolves it; after Load it
+	// still names the direct parent.
+	Extends string
+
+	// ResponseSchema is the response-schema frontmatter value: a schema file
+	// path (relative to the agent file) or, for an inline YAML mapping, the
+	// schema encoded as JSON.
+	ResponseSchema string
+
+	// Vars holds the template variable defaults from the vars frontmatter
+	// mapping.  Variables declared without a value are required and have no
+	// entry.
+	Vars map[string]string
+
+	// Template reports whether the body (and the run's prompt file) is
+	// rendered as a template: the template frontmatter key, which defaults
+	// to true when vars is set.  Otherwise only include directives are
+	// expanded, unless --var is given.
+	Template bool
+
+	// Tools and Skills are the tools and skills frontmatter lists.  Tools is
+	// an allowlist of tool names (a built-in tool or a skill name) offered to the
+	// model; Skills selects which discovered skills are loaded at all.  Nil
+	// means the key is absent (no restriction); an empty list allows nothing.
+	// DisableTools offers no tools regardless of the lists.
+	Tools        []string
+	Skills       []string
+	DisableTools bool
 }
 
-// ParseFile loads and parses an agent file from disk.
+// ParseFile loads and parses a single agent file from disk without following
+// extends; Load resolves the inheritance chain.
 func ParseFile(path string) (Agent, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return Agent{}, err
 	}
-	return Parse(string(data))
+	ag, err := Parse(string(data))
+	if err != nil {
+		return Agent{}, err
+	}
+	ag.Path = path
+	return ag, nil
 }
 
-// Parse reads agent file content and returns the parsed agent.
+// Parse reads agent file content and returns the parsed agent.  An extends
+// key is recorded in Extends but not resolved; Load follows it.
 func Parse(content string) (Agent, error) {
-	content = strings.ReplaceAll(content, "\r\n", "\n")
-	content = strings.TrimPrefix(content, "\ufeff")
-
-	if !strings.HasPrefix(content, "---\n") && content != "---" && !strings.HasPrefix(content, "---\r\n") {
+	front, body, warnings, err := splitFrontmatter(content)
+	if err != nil {
+		return Agent{}, err
+	}
+	if front == nil {
 		return Agent{
-			SystemPrompt: content,
+			SystemPrompt: body,
 			Config:       map[string]string{},
 			Warnings:     nil,
 		}, nil
 	}
+	ag, err := build(front, body)
+	if err != nil {
+		return Agent{}, err
+	}
+	ag.Warnings = warnings
+	return ag, nil
+}
+
+// splitFrontmatter separates the YAML frontmatter from the body and checks
+// it against the frontmatter schema: schema errors fail, warnings (unknown
+// keys, ...) are returned.  front is nil when the content has no
+// frontmatter.
+func splitFrontmatter(content string) (front map[string]interface{}, body string, warnings []string, err error) {
+	front, body, issues, err := parseFrontmatter(content)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if err := issueErrors(issues); err != nil {
+		return nil, "", nil, err
+	}
+	for _, i := range issues {
+		warnings = append(warnings, i.Message)
+	}
+	return front, body, warnings, nil
+}
+
+// parseFrontmatter is splitFrontmatter, but returns every schema issue
+// instead of failing on errors.  err is set only when the frontmatter cannot
+// be parsed at all.
+func parseFrontmatter(content string) (front map[string]interface{}, body string, issues []Issue, err error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.TrimPrefix(content, "\ufeff")
+
+	if !strings.HasPrefix(content, "---\n") && content != "---" && !strings.HasPrefix(content, "---\r\n") {
+		return nil, content, nil, nil
+	}
 
 	lines := strings.Split(content, "\n")
 	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
-		return Agent{SystemPrompt: content, Config: map[string]string{}}, nil
+		return nil, content, nil, nil
 	}
 
 	end := -1
@@ -70,20 +141,39 @@ func Parse(content string) (Agent, error) {
 		}
 	}
 	if end == -1 {
-		return Agent{}, errors.New("agent frontmatter missing closing delimiter")
+		return nil, "", nil, errors.New("agent frontmatter missing closing delimiter")
 	}
 
 	yamlBlock := strings.Join(lines[1:end], "\n")
-	body := strings.Join(lines[end+1:], "\n")
+	body = strings.Join(lines[end+1:], "\n")
 	body = strings.TrimPrefix(body, "\n")
 
-	parsed := map[string]interface{}{}
-	if err := yaml.Unmarshal([]byte(yamlBlock), &parsed); err != nil {
-		return Agent{}, fmt.Errorf("invalid agent frontmatter: %w", err)
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlBlock), &doc); err != nil {
+		return nil, "", nil, fmt.Errorf("invalid agent frontmatter: %w", err)
+	}
+	front = map[string]interface{}{}
+	if len(doc.Content) == 0 {
+		return front, body, nil, nil
+	}
+	root := doc.Content[0]
+	if issues = checkFrontmatter(root); issueErrors(issues) != nil {
+		return nil, body, issues, nil
 	}
+	if err := root.Decode(&front); err != nil {
+		return nil, "", nil, fmt.Errorf("invalid agent frontmatter: %w", err)
+	}
+	return front, body, issues, nil
+}
 
+// build converts parsed frontmatter and body into an Agent.
+func build(parsed map[string]interface{}, body string) (Agent, error) {
 	config := map[string]string{}
-	warnings := []string{}
+	var responseSchema, name, description, extends string
+	var vars map[string]string
+	var tools, skillNames []string
+	var disableTools, hasVars bool
+	var template *bool
 	keys := make([]string, 0, len(parsed))
 	for key := range parsed {
 		keys = append(keys, key)
@@ -91,15 +181,155 @@ func Parse(content string) (Agent, error) {
 	sort.Strings(keys)
 	for _, key := range keys {
 		value := parsed[key]
-		config[key] = fmt.Sprint(value)
-		if _, ok := knownKeys[key]; !ok {
-			warnings = append(warnings, fmt.Sprintf("unknown agent key: %s", key))
+		if key == "response-schema" || key == "response_schema" {
+			s, err := schemaValue(value)
+			if err != nil {
+				return Agent{}, err
+			}
+			responseSchema = s
+			continue
+		}
+		if key == "vars" {
+			v, err := varsValue(value)
+			if err != nil {
+				return Agent{}, err
+			}
+			vars, hasVars = v, true
+			continue
 		}
+		if key == "template" {
+			b, ok := value.(bool)
+			if !ok {
+				return Agent{}, fmt.Errorf("template must be true or false")
+			}
+			template = &b
+			continue
+		}
+		if key == "extends" {
+			s, ok := value.(string)
+			if !ok || strings.TrimSpace(s) == "" {
+				return Agent{}, fmt.Errorf("extends must be an agent file path or name")
+			}
+			extends = strings.TrimSpace(s)
+			continue
+		}
+		if key == "prompt-placement" || key == "prompt_placement" {
+			if _, err := placementValue(value); err != nil {
+				return Agent{}, err
+			}
+			continue
+		}
+		if key == "tools" || key == "skills" {
+			list, err := namesValue(key, value)
+			if err != nil {
+				return Agent{}, err
+			}
+			if key == "tools" {
+				tools = list
+			} else {
+				skillNames = list
+			}
+			continue
+		}
+		if key == "disable-tools" || key == "disable_tools" {
+			b, ok := value.(bool)
+			if !ok {
+				return Agent{}, fmt.Errorf("%s must be true or false", key)
+			}
+			disableTools = b
+			continue
+		}
+		if key == "name" || key == "description" {
+			s, ok := value.(string)
+			if !ok {
+				return Agent{}, fmt.Errorf("%s must be a string", key)
+			}
+			if key == "name" {
+				name = strings.TrimSpace(s)
+			} else {
+				description = strings.TrimSpace(s)
+			}
+			continue
+		}
+		// Unknown keys are reported by the schema check; only single values
+		// can be passed on as config.
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			if !knownKey(key) {
+				continue
+			}
+		}
+		config[key] = fmt.Sprint(value)
 	}
 
+	isTemplate := hasVars
+	if template != nil {
+		isTemplate = *template
+	}
 	return Agent{
-		SystemPrompt: body,
-		Config:       config,
-		Warnings:     warnings,
+		SystemPrompt:   body,
+		Template:       isTemplate,
+		Config:         config,
+		ResponseSchema: responseSchema,
+		Vars:           vars,
+		Name:           name,
+		Description:    description,
+		Extends:        extends,
+		Tools:          tools,
+		Skills:         skillNames,
+		DisableTools:   disableTools,
 	}, nil
 }
+
+// namesValue converts a tools or skills list to names.  A null value is an
+// empty list.
+func namesValue(key string, value interface{}) ([]string, error) {
+	if value == nil {
+		return []string{}, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of names", key)
+	}
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("%s must be a list of names, got %v", key, item)
+		}
+		names = append(names, strings.TrimSpace(s))
+	}
+	return names, nil
+}
+
+// varsValue converts the vars mapping to strings, dropping null values.
+func varsValue(value interface{}) (map[string]string, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("vars must be a mapping of name to default value")
+	}
+	vars := map[string]string{}
+	for name, v := range m {
+		if v == nil {
+			continue
+		}
+		vars[name] = fmt.Sprint(v)
+	}
+	return vars, nil
+}
+
+// schemaValue normalizes a response-schema value: strings are paths, mappings
+// are inline schemas and are re-encoded as JSON.
+func schemaValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v), nil
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid response-schema: %w", err)
+		}
+		return string(data), nil
+	}
+	return "", fmt.Errorf("response-schema must be a file path or a mapping")
+}
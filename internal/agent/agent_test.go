@@ -1,6 +1,9 @@
 package agent
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -61,6 +64,85 @@ func TestParseFrontmatter(t *testing.T) {
 	}
 }
 
+func TestParseSchemaErrors(t *testing.T) {
+	cases := map[string]string{
+		"---\nmodel: gpt-4\ntemperature: hot\n---\n":     `line 3, column 14: temperature must be a number, got "hot"`,
+		"---\ntemperature: 3\n---\n":                     "line 2, column 14: temperature must be between 0 and 2, got 3",
+		"---\nmax-tokens: 1.5\n---\n":                    `line 2, column 13: max-tokens must be an integer, got "1.5"`,
+		"---\nmodel:\n  name: x\n---\n":                  "line 3, column 3: model must be a single value, got a mapping",
+		"---\nprompt-placement: after\n---\n":            `prompt-placement must be one of append, prepend, replace, got "after"`,
+		"---\ntools: [terminal, [x]]\n---\n":             "tools[1] must be a single value, got a list",
+		"---\nvars:\n  lang: [Go]\n---\n":                "line 3, column 9: vars.lang must be a single value, got a list",
+		"---\ndisable_tools: 1\n---\n":                   `disable_tools must be true or false, got "1"`,
+		"---\n- model\n---\n":                            "frontmatter must be a mapping",
+		"---\nmodel: x\ntemperature: x\ntop-p: 2\n---\n": "temperature must be a number, got \"x\"; line 4, column 8: top-p must be between 0 and 1",
+	}
+	for content, want := range cases {
+		_, err := Parse(content)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestParseUnknownKeys(t *testing.T) {
+	parsed, err := Parse("---\ntemprature: 0.5\nextra:\n  nested: true\nmax-tokens: 10\nmax_tokens: 20\n---\nBody\n")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []string{
+		"unknown agent key: temprature (did you mean temperature?)",
+		"unknown agent key: extra (ignored: not a single value)",
+		"max_tokens duplicates max-tokens; the later value wins",
+	}
+	if strings.Join(parsed.Warnings, "|") != strings.Join(want, "|") {
+		t.Fatalf("Warnings = %q", parsed.Warnings)
+	}
+	if _, ok := parsed.Config["extra"]; ok {
+		t.Fatalf("nested unknown value leaked into config: %v", parsed.Config)
+	}
+	if parsed.Config["temprature"] != "0.5" {
+		t.Fatalf("unknown single values are still passed on: %v", parsed.Config)
+	}
+}
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	path := writeAgent(t, dir, "a.md", "---\nmodel: gpt-4\ntemprature: 0.5\ntop_p: high\nextends: missing.md\n---\nBody\n")
+	issues, err := Lint(path, dir)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	var got []string
+	for _, i := range issues {
+		got = append(got, fmt.Sprintf("%d:%d %s %s", i.Line, i.Column, i.Severity, i.Message))
+	}
+	want := []string{
+		"3:1 warning unknown agent key: temprature (did you mean temperature?)",
+		`4:8 error top_p must be a number, got "high"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("issues =\n%s", strings.Join(got, "\n"))
+	}
+
+	// Once the schema passes, a broken extends chain is reported at the key.
+	writeAgent(t, dir, "a.md", "---\nmodel: gpt-4\nextends: missing.md\n---\n")
+	issues, _ = Lint(path, dir)
+	if len(issues) != 1 || issues[0].Line != 3 || issues[0].Severity != SeverityError || !strings.Contains(issues[0].Message, "missing.md") {
+		t.Fatalf("issues = %+v", issues)
+	}
+
+	writeAgent(t, dir, "a.md", "---\nmodel: gpt-4\n  bad: [\n---\n")
+	issues, _ = Lint(path, dir)
+	if len(issues) != 1 || issues[0].Line < 2 || !strings.Contains(issues[0].Message, "invalid agent frontmatter") {
+		t.Fatalf("syntax issues = %+v", issues)
+	}
+
+	if _, err := Lint(filepath.Join(dir, "nope.md"), dir); err == nil {
+		t.Fatal("expected read error")
+	}
+}
+
 func TestParseFrontmatterMissingDelimiter(t *testing.T) {
 	content := strings.Join([]string{
 		"---",
@@ -73,3 +155,278 @@ func TestParseFrontmatterMissingDelimiter(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestParseResponseSchema(t *testing.T) {
+	parsed, err := Parse("---\nresponse-schema: schemas/out.json\n---\nBody\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.ResponseSchema != "schemas/out.json" {
+		t.Fatalf("ResponseSchema = %q", parsed.ResponseSchema)
+	}
+	if _, ok := parsed.Config["response-schema"]; ok {
+		t.Fatalf("response-schema should not be a config value")
+	}
+
+	inline := strings.Join([]string{
+		"---",
+		"response-schema:",
+		"  type: object",
+		"  required: [title]",
+		"---",
+		"Body",
+	}, "\n")
+	parsed, err = Parse(inline)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.ResponseSchema != `{"required":["title"],"type":"object"}` {
+		t.Fatalf("inline ResponseSchema = %q", parsed.ResponseSchema)
+	}
+
+	if _, err := Parse("---\nresponse-schema: [1, 2]\n---\n"); err == nil {
+		t.Fatalf("expected error for list schema")
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	parsed, err := Parse("---\nvars:\n  lang: Go\n  depth: 2\n  ticket:\n---\nReview {{.lang}}\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Vars["lang"] != "Go" || parsed.Vars["depth"] != "2" {
+		t.Fatalf("Vars = %v", parsed.Vars)
+	}
+	if _, ok := parsed.Vars["ticket"]; ok {
+		t.Fatalf("required var should have no default: %v", parsed.Vars)
+	}
+	if _, ok := parsed.Config["vars"]; ok || len(parsed.Warnings) > 0 {
+		t.Fatalf("vars leaked into config: %v %v", parsed.Config, parsed.Warnings)
+	}
+
+	if _, err := Parse("---\nvars: [a, b]\n---\n"); err == nil {
+		t.Fatalf("expected error for list vars")
+	}
+}
+
+func writeAgent(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseNameDescription(t *testing.T) {
+	parsed, err := Parse("---\nname: reviewer\ndescription: Reviews diffs\nmodel: m\n---\nBody\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Name != "reviewer" || parsed.Description != "Reviews diffs" {
+		t.Fatalf("metadata = %q, %q", parsed.Name, parsed.Description)
+	}
+	if _, ok := parsed.Config["name"]; ok || len(parsed.Warnings) > 0 {
+		t.Fatalf("metadata leaked into config: %v %v", parsed.Config, parsed.Warnings)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	base := t.TempDir()
+	dir := AgentsDir(base)
+	writeAgent(t, dir, "code-reviewer.md", "---\nname: reviewer\ndescription: Reviews diffs\n---\nReview.\n")
+	writeAgent(t, dir, "writer.md", "Write docs.\n")
+	writeAgent(t, dir, "broken.md", "---\nmodel: [\n---\n")
+	writeAgent(t, dir, "zz-dup.md", "---\nname: writer\n---\nDup.\n")
+	writeAgent(t, dir, "notes.txt", "not an agent")
+
+	agents, warnings, err := Discover(base)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if len(agents) != 2 || agents[0].Name != "reviewer" || agents[1].Name != "writer" {
+		t.Fatalf("agents = %+v", agents)
+	}
+	if agents[1].SystemPrompt != "Write docs.\n" || agents[0].Path != filepath.Join(dir, "code-reviewer.md") {
+		t.Fatalf("agents = %+v", agents)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "broken.md") || !strings.Contains(warnings[1], "already used") {
+		t.Fatalf("warnings = %v", warnings)
+	}
+
+	list := FormatList(agents)
+	if !strings.Contains(list, "reviewer\n  Reviews diffs\n") || !strings.Contains(list, "(no description)") {
+		t.Fatalf("list = %q", list)
+	}
+}
+
+func TestDiscoverMissingDir(t *testing.T) {
+	agents, warnings, err := Discover(t.TempDir())
+	if err != nil || agents != nil || warnings != nil {
+		t.Fatalf("got %v, %v, %v", agents, warnings, err)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	base := t.TempDir()
+	path := writeAgent(t, AgentsDir(base), "code-reviewer.md", "---\nname: reviewer\n---\nReview.\n")
+
+	if got, err := ResolvePath(base, "reviewer"); err != nil || got != path {
+		t.Fatalf("ResolvePath(name) = %q, %v", got, err)
+	}
+	if got, err := ResolvePath(base, "./other.md"); err != nil || got != "./other.md" {
+		t.Fatalf("ResolvePath(path) = %q, %v", got, err)
+	}
+	if _, err := ResolvePath(base, "missing"); err == nil || !strings.Contains(err.Error(), "rai agents list") {
+		t.Fatalf("expected not-found error, got %v", err)
+	}
+}
+
+func TestLoadExtendsMergesParent(t *testing.T) {
+	dir := t.TempDir()
+	writeAgent(t, dir, "base/base.md", "---\nname: base\nmodel: gpt-4\nmax-tokens: 100\ntemperature: 0.2\nresponse-schema: out.json\nvars:\n  lang: Go\n  tone: formal\n---\nBase rules.\n")
+	child := writeAgent(t, dir, "child.md", "---\nextends: base/base.md\nmax_tokens: 500\nvars:\n  tone: casual\n---\nReview diffs.\n")
+
+	ag, err := Load(child, dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if ag.SystemPrompt != "Base rules.\n\nReview diffs.\n" {
+		t.Fatalf("SystemPrompt = %q", ag.SystemPrompt)
+	}
+	if ag.Config["model"] != "gpt-4" || ag.Config["max_tokens"] != "500" || ag.Config["temperature"] != "0.2" {
+		t.Fatalf("Config = %v", ag.Config)
+	}
+	if _, ok := ag.Config["max-tokens"]; ok {
+		t.Fatalf("parent max-tokens should be overridden by max_tokens: %v", ag.Config)
+	}
+	if ag.Vars["lang"] != "Go" || ag.Vars["tone"] != "casual" {
+		t.Fatalf("Vars = %v", ag.Vars)
+	}
+	if ag.ResponseSchema != filepath.Join(dir, "base", "out.json") {
+		t.Fatalf("ResponseSchema = %q", ag.ResponseSchema)
+	}
+	if ag.Name != "" || ag.Extends != "base/base.md" || ag.Path != child {
+		t.Fatalf("metadata = %q %q %q", ag.Name, ag.Extends, ag.Path)
+	}
+}
+
+func TestParseToolSettings(t *testing.T) {
+	ag, err := Parse("---\ntools: [terminal, lint]\nskills: []\ndisable-tools: true\n---\nBody\n")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if strings.Join(ag.Tools, ",") != "terminal,lint" || ag.Skills == nil || len(ag.Skills) != 0 || !ag.DisableTools {
+		t.Fatalf("tools = %v, skills = %#v, disable = %v", ag.Tools, ag.Skills, ag.DisableTools)
+	}
+	if len(ag.Config) != 0 || len(ag.Warnings) != 0 {
+		t.Fatalf("tool settings should not reach Config: %v %v", ag.Config, ag.Warnings)
+	}
+
+	plain, _ := Parse("---\nmodel: x\n---\nBody\n")
+	if plain.Tools != nil || plain.Skills != nil || plain.DisableTools {
+		t.Fatalf("absent keys should not restrict tools: %+v", plain)
+	}
+
+	for _, content := range []string{"---\ntools: terminal\n---\n", "---\nskills: [1]\n---\n", "---\ndisable-tools: yes please\n---\n"} {
+		if _, err := Parse(content); err == nil {
+			t.Errorf("expected error for %q", content)
+		}
+	}
+}
+
+func TestLoadRebasesIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeAgent(t, dir, "base/base.md", "Base.\n@file:style.md\n")
+	child := writeAgent(t, dir, "child.md", "---\nextends: base/base.md\n---\n{{include \"docs/*.md\"}}\n")
+
+	ag, err := Load(child, dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := fmt.Sprintf("Base.\n{{include %q}}\n\n{{include %q}}\n", filepath.Join(dir, "base", "style.md"), filepath.Join(dir, "docs", "*.md"))
+	if ag.SystemPrompt != want {
+		t.Fatalf("SystemPrompt = %q, want %q", ag.SystemPrompt, want)
+	}
+	if got := strings.Join(ag.Dirs, ","); got != dir+","+filepath.Join(dir, "base") {
+		t.Errorf("Dirs = %s", got)
+	}
+}
+
+func TestLoadExtendsPlacement(t *testing.T) {
+	dir := t.TempDir()
+	writeAgent(t, dir, "base.md", "Parent.\n")
+	for placement, want := range map[string]string{
+		"":        "Parent.\n\nChild.\n",
+		"append":  "Parent.\n\nChild.\n",
+		"prepend": "Child.\n\nParent.\n",
+		"replace": "Child.\n",
+	} {
+		front := "extends: base.md\n"
+		if placement != "" {
+			front += "prompt-placement: " + placement + "\n"
+		}
+		child := writeAgent(t, dir, "child.md", "---\n"+front+"---\nChild.\n")
+		ag, err := Load(child, dir)
+		if err != nil {
+			t.Fatalf("%s: Load: %v", placement, err)
+		}
+		if ag.SystemPrompt != want {
+			t.Errorf("%s: SystemPrompt = %q, want %q", placement, ag.SystemPrompt, want)
+		}
+	}
+
+	if _, err := Parse("---\nprompt-placement: middle\n---\n"); err == nil {
+		t.Fatal("expected error for invalid prompt-placement")
+	}
+}
+
+func TestLoadExtendsByName(t *testing.T) {
+	base := t.TempDir()
+	writeAgent(t, AgentsDir(base), "house-style.md", "---\nname: house\nmodel: m1\n---\nHouse style.\n")
+	child := writeAgent(t, base, "agents/reviewer.md", "---\nextends: house\n---\nReview.\n")
+
+	ag, err := Load(child, base)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if ag.Config["model"] != "m1" || ag.SystemPrompt != "House style.\n\nReview.\n" {
+		t.Fatalf("agent = %+v", ag)
+	}
+
+	missing := writeAgent(t, base, "agents/orphan.md", "---\nextends: nobody\n---\n")
+	if _, err := Load(missing, base); err == nil || !strings.Contains(err.Error(), `agent "nobody" not found`) {
+		t.Fatalf("expected not-found error, got %v", err)
+	}
+}
+
+func TestLoadExtendsErrors(t *testing.T) {
+	dir := t.TempDir()
+	a := writeAgent(t, dir, "a.md", "---\nextends: b.md\n---\nA\n")
+	writeAgent(t, dir, "b.md", "---\nextends: a.md\n---\nB\n")
+	_, err := Load(a, dir)
+	if err == nil || !strings.Contains(err.Error(), "extends cycle: a.md -> b.md -> a.md") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	self := writeAgent(t, dir, "self.md", "---\nextends: ./self.md\n---\n")
+	if _, err := Load(self, dir); err == nil || !strings.Contains(err.Error(), "extends cycle") {
+		t.Fatalf("expected self cycle error, got %v", err)
+	}
+
+	writeAgent(t, dir, "broken.md", "---\nvars: [x]\n---\n")
+	mid := writeAgent(t, dir, "mid.md", "---\nextends: broken.md\n---\n")
+	top := writeAgent(t, dir, "top.md", "---\nextends: mid.md\n---\n")
+	_, err = Load(top, dir)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, part := range []string{top, "extends mid.md", mid, "extends broken.md", "broken.md: invalid agent frontmatter: line 2, column 7: vars must be a mapping"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("error %q does not mention %q", err, part)
+		}
+	}
+}
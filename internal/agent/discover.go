@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	raiDirName    = ".rai"
+	agentsDirName = "agents"
+	agentFileExt  = ".md"
+)
+
+// AgentsDir returns the path to the named agent library for a base directory.
+func AgentsDir(baseDir string) string {
+	return filepath.Join(baseDir, raiDirName, agentsDirName)
+}
+
+// Discover scans .rai/agents/ for agent files.  Each *.md file is an agent
+// named by its name frontmatter key, or by its file name without .md, and is
+// loaded with its extends chain resolved.  Unloadable files and duplicate
+// names are collected as warnings rather than hard errors so that one bad
+// file doesn't hide the rest; for a duplicate name the first file in name
+// order wins.
+func Discover(baseDir string) ([]Agent, []string, error) {
+	scanned, warnings, err := scan(baseDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var agents []Agent
+	for _, entry := range scanned {
+		ag, err := Load(entry.Path, baseDir)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("agent %s: %v", filepath.Base(entry.Path), err))
+			continue
+		}
+		ag.Name = entry.Name
+		agents = append(agents, ag)
+	}
+	return agents, warnings, nil
+}
+
+// scan parses every agent file on its own, without following extends, and
+// assigns names.  It is the name index for both Discover and extends.
+func scan(baseDir string) ([]Agent, []string, error) {
+	dir := AgentsDir(baseDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("reading agents directory: %w", err)
+	}
+
+	var agents []Agent
+	var warnings []string
+	seen := map[string]string{}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), agentFileExt) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		ag, err := ParseFile(path)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("agent %s: %v", e.Name(), err))
+			continue
+		}
+		if ag.Name == "" {
+			ag.Name = strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		}
+		if first, ok := seen[ag.Name]; ok {
+			warnings = append(warnings, fmt.Sprintf("agent %s: name %q is already used by %s", e.Name(), ag.Name, first))
+			continue
+		}
+		seen[ag.Name] = e.Name()
+		agents = append(agents, ag)
+	}
+
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	return agents, warnings, nil
+}
+
+// Find returns the discovered agent with the given name.
+func Find(baseDir, name string) (Agent, error) {
+	path, err := namedPath(baseDir, name)
+	if err != nil {
+		return Agent{}, err
+	}
+	ag, err := Load(path, baseDir)
+	if err != nil {
+		return Agent{}, err
+	}
+	ag.Name = name
+	return ag, nil
+}
+
+// namedPath returns the file of the agent with the given name.
+func namedPath(baseDir, name string) (string, error) {
+	agents, _, err := scan(baseDir)
+	if err != nil {
+		return "", err
+	}
+	for _, ag := range agents {
+		if ag.Name == name {
+			return ag.Path, nil
+		}
+	}
+	return "", fmt.Errorf("agent %q not found in %s ('rai agents list' shows the available agents)", name, filepath.Join(raiDirName, agentsDirName))
+}
+
+// ResolvePath turns an --agent value into a file path.  An existing file, or
+// anything that looks like a path (a separator or a .md extension), is used
+// as given; otherwise ref is looked up by name in .rai/agents/.
+func ResolvePath(baseDir, ref string) (string, error) {
+	if ref == "" || looksLikePath(ref) {
+		return ref, nil
+	}
+	if info, err := os.Stat(ref); err == nil && !info.IsDir() {
+		return ref, nil
+	}
+	return namedPath(baseDir, ref)
+}
+
+func looksLikePath(ref string) bool {
+	return strings.ContainsAny(ref, `/\`) || strings.EqualFold(filepath.Ext(ref), agentFileExt)
+}
+
+// FormatList returns a human-readable listing of agents for `rai agents list`.
+func FormatList(agents []Agent) string {
+	if len(agents) == 0 {
+		return "no agents found"
+	}
+
+	var b strings.Builder
+	for i, ag := range agents {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		description := ag.Description
+		if description == "" {
+			description = "(no description)"
+		}
+		b.WriteString(fmt.Sprintf("%s\n  %s\n  %s", ag.Name, description, ag.Path))
+	}
+	return b.String()
+}
+
+// FormatShow returns the details of one agent for `rai agents show`: its
+// metadata, frontmatter settings and system prompt.
+func FormatShow(ag Agent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "name: %s\n", ag.Name)
+	if ag.Description != "" {
+		fmt.Fprintf(&b, "description: %s\n", ag.Description)
+	}
+	fmt.Fprintf(&b, "path: %s\n", ag.Path)
+
+	keys := make([]string, 0, len(ag.Config))
+	for key := range ag.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", key, ag.Config[key])
+	}
+	if ag.ResponseSchema != "" {
+		fmt.Fprintf(&b, "response-schema: %s\n", ag.ResponseSchema)
+	}
+	if ag.Tools != nil {
+		fmt.Fprintf(&b, "tools: %s\n", strings.Join(ag.Tools, ", "))
+	}
+	if ag.Skills != nil {
+		fmt.Fprintf(&b, "skills: %s\n", strings.Join(ag.Skills, ", "))
+	}
+	if ag.DisableTools {
+		b.WriteString("disable-tools: true\n")
+	}
+	if len(ag.Vars) > 0 {
+		names := make([]string, 0, len(ag.Vars))
+		for name := range ag.Vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "var %s: %s\n", name, ag.Vars[name])
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(strings.TrimRight(ag.SystemPrompt, "\n"))
+	return b.String()
+}
@@ -0,0 +1,230 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"run-ai/internal/render"
+)
+
+// Prompt placements for an agent that extends another: where the child's
+// body goes relative to the parent's system prompt.
+const (
+	PlacementAppend  = "append"  // parent prompt, then child body (default)
+	PlacementPrepend = "prepend" // child body, then parent prompt
+	PlacementReplace = "replace" // child body only
+)
+
+// maxExtendsDepth bounds inheritance chains independently of cycle
+// detection, so a runaway chain of distinct files still fails clearly.
+const maxExtendsDepth = 16
+
+// childOnlyKeys are never inherited from a parent.
+var childOnlyKeys = []string{"extends", "prompt-placement", "prompt_placement", "name", "description"}
+
+// Load reads the agent file at path and resolves its extends chain.  Parent
+// paths are relative to the extending file; other values are agent names
+// looked up in baseDir's .rai/agents/.  The parent's frontmatter is
+// deep-merged under the child's (mappings merge key by key, anything else is
+// replaced by the child), and the system prompts are composed according to
+// the child's prompt-placement.  Include directives in each body are
+// rebased to absolute paths, so they resolve relative to the file that
+// contains them.  Errors name every file in the chain.
+func Load(path, baseDir string) (Agent, error) {
+	doc, err := loadDocument(path, baseDir, nil)
+	if err != nil {
+		return Agent{}, err
+	}
+	var ag Agent
+	if doc.front == nil {
+		ag = Agent{SystemPrompt: doc.body, Config: map[string]string{}}
+	} else if ag, err = build(doc.front, doc.body); err != nil {
+		return Agent{}, fmt.Errorf("%s: %w", path, err)
+	}
+	ag.Path = path
+	ag.Dirs = doc.dirs
+	ag.Warnings = doc.warnings
+	return ag, nil
+}
+
+// document is an agent file with its extends chain resolved.
+type document struct {
+	front    map[string]interface{} // merged frontmatter; nil when there is none
+	body     string                 // composed system prompt
+	warnings []string               // frontmatter warnings of every file in the chain
+	dirs     []string               // absolute directory of every file in the chain
+}
+
+// loadDocument returns the agent at path merged with its parents.  A
+// parent's warnings are prefixed with its file name.  chain holds the
+// absolute paths of the files extending it.
+func loadDocument(path, baseDir string, chain []string) (document, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return document{}, err
+	}
+	for _, seen := range chain {
+		if seen == abs {
+			return document{}, fmt.Errorf("extends cycle: %s", cycleString(append(chain, abs)))
+		}
+	}
+	if len(chain) >= maxExtendsDepth {
+		return document{}, fmt.Errorf("extends chain deeper than %d files", maxExtendsDepth)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return document{}, err
+	}
+	front, body, warnings, err := splitFrontmatter(string(data))
+	if err != nil {
+		return document{}, fmt.Errorf("%s: %w", path, err)
+	}
+	body = render.RebaseIncludes(body, filepath.Dir(abs))
+	doc := document{front: front, body: body, warnings: warnings, dirs: []string{filepath.Dir(abs)}}
+	if front == nil {
+		doc.warnings = nil
+		return doc, nil
+	}
+	// Validate this file on its own so errors point at the file that has
+	// them rather than at the merged result.
+	self, err := build(front, body)
+	if err != nil {
+		return document{}, fmt.Errorf("%s: %w", path, err)
+	}
+	rebaseSchemaPath(front, filepath.Dir(path))
+	if self.Extends == "" {
+		return doc, nil
+	}
+
+	parentPath, err := resolveExtends(self.Extends, path, baseDir)
+	if err != nil {
+		return document{}, fmt.Errorf("%s: extends %s: %w", path, self.Extends, err)
+	}
+	parent, err := loadDocument(parentPath, baseDir, append(chain, abs))
+	if err != nil {
+		return document{}, fmt.Errorf("%s: extends %s: %w", path, self.Extends, err)
+	}
+
+	placement, _ := placementValue(lookupKey(front, "prompt-placement"))
+	doc.front = deepMerge(withoutKeys(parent.front, childOnlyKeys), front)
+	doc.body = composePrompt(parent.body, body, placement)
+	for _, w := range parent.warnings {
+		doc.warnings = append(doc.warnings, filepath.Base(parentPath)+": "+w)
+	}
+	doc.dirs = append(doc.dirs, parent.dirs...)
+	return doc, nil
+}
+
+// resolveExtends returns the parent agent file for an extends value.
+func resolveExtends(ref, childPath, baseDir string) (string, error) {
+	if looksLikePath(ref) {
+		if filepath.IsAbs(ref) {
+			return ref, nil
+		}
+		return filepath.Join(filepath.Dir(childPath), ref), nil
+	}
+	return namedPath(baseDir, ref)
+}
+
+// composePrompt places the child body relative to the parent prompt.
+func composePrompt(parent, child, placement string) string {
+	parent = strings.TrimRight(parent, "\n")
+	trimmed := strings.TrimRight(child, "\n")
+	switch {
+	case placement == PlacementReplace:
+		return child
+	case trimmed == "":
+		return parent
+	case parent == "":
+		return child
+	case placement == PlacementPrepend:
+		return trimmed + "\n\n" + parent + "\n"
+	}
+	return parent + "\n\n" + child
+}
+
+// placementValue validates a prompt-placement value; nil means the default.
+func placementValue(value interface{}) (string, error) {
+	if value == nil {
+		return PlacementAppend, nil
+	}
+	s, _ := value.(string)
+	switch s {
+	case PlacementAppend, PlacementPrepend, PlacementReplace:
+		return s, nil
+	}
+	return "", fmt.Errorf("prompt-placement must be one of append, prepend, replace, got %v", value)
+}
+
+// deepMerge returns base overlaid with over: nested mappings are merged
+// recursively, any other value in over replaces the one in base.  A key in
+// over also replaces its hyphen/underscore spelling in base, so max_tokens
+// in a child overrides max-tokens in its parent.
+func deepMerge(base, over map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		if alt := altSpelling(k); alt != k {
+			delete(out, alt)
+		}
+		baseMap, baseOK := out[k].(map[string]interface{})
+		overMap, overOK := v.(map[string]interface{})
+		if baseOK && overOK {
+			out[k] = deepMerge(baseMap, overMap)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// rebaseSchemaPath makes a relative response-schema path absolute so it
+// still resolves after the frontmatter is merged into a child in another
+// directory.
+func rebaseSchemaPath(front map[string]interface{}, dir string) {
+	for _, key := range []string{"response-schema", "response_schema"} {
+		if p, ok := front[key].(string); ok && strings.TrimSpace(p) != "" && !filepath.IsAbs(strings.TrimSpace(p)) {
+			if abs, err := filepath.Abs(filepath.Join(dir, strings.TrimSpace(p))); err == nil {
+				front[key] = abs
+			}
+		}
+	}
+}
+
+func lookupKey(front map[string]interface{}, key string) interface{} {
+	if v, ok := front[key]; ok {
+		return v
+	}
+	return front[altSpelling(key)]
+}
+
+func altSpelling(key string) string {
+	if strings.Contains(key, "-") {
+		return strings.ReplaceAll(key, "-", "_")
+	}
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+func withoutKeys(m map[string]interface{}, keys []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	for _, k := range keys {
+		delete(out, k)
+	}
+	return out
+}
+
+func cycleString(chain []string) string {
+	names := make([]string, len(chain))
+	for i, p := range chain {
+		names[i] = filepath.Base(p)
+	}
+	return strings.Join(names, " -> ")
+}
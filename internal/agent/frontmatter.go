@@ -0,0 +1,368 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue severities.  Errors stop an agent from loading; warnings do not,
+// unless the run is strict.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a frontmatter problem at a position in the agent file.  Line and
+// Column are 1-based and count the opening "---" line; Column is 0 when only
+// the line is known.
+type Issue struct {
+	Line     int
+	Column   int
+	Severity string
+	Message  string
+}
+
+func (i Issue) String() string {
+	if i.Column > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", i.Line, i.Column, i.Message)
+	}
+	return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+}
+
+// fieldKind is the type a frontmatter value must have.
+type fieldKind int
+
+const (
+	kindString  fieldKind = iota // any scalar, used as text
+	kindNumber                   // int or float
+	kindInteger                  // int
+	kindBool                     // true or false
+	kindEnum                     // one of field.enum
+	kindList                     // sequence of field.items
+	kindObject                   // mapping of field.fields, or of field.values for free-form keys
+	kindSchema                   // a file path or an inline mapping (response-schema)
+)
+
+// field describes one frontmatter value.  The schema is a tree so that
+// nested objects can be declared the same way as top-level keys.
+type field struct {
+	kind     fieldKind
+	enum     []string
+	min, max *float64
+	items    *field
+	fields   map[string]*field
+	values   *field // kindObject with free-form keys
+	nullable bool   // null is accepted (vars entries without a default)
+}
+
+func bound(v float64) *float64 { return &v }
+
+// frontmatterSchema lists every key an agent file may set, in its
+// hyphenated spelling; the underscore spelling is accepted too.  Keys that
+// are not listed load with a warning, as before.
+var frontmatterSchema = &field{kind: kindObject, fields: map[string]*field{
+	// Config values, passed on to the provider.
+	"api-key":           {kind: kindString},
+	"endpoint":          {kind: kindString},
+	"model":             {kind: kindString},
+	"org":               {kind: kindString},
+	"organization":      {kind: kindString},
+	"provider":          {kind: kindString},
+	"reasoning-summary": {kind: kindString},
+	"temperature":       {kind: kindNumber, min: bound(0), max: bound(2)},
+	"top-p":             {kind: kindNumber, min: bound(0), max: bound(1)},
+	"max-tokens":        {kind: kindInteger, min: bound(1)},
+	"max-output-tokens": {kind: kindInteger, min: bound(1)},
+	"tool-choice":       {kind: kindString},
+	"schema-retries":    {kind: kindInteger, min: bound(0)},
+
+	// Agent settings, interpreted by rai itself.
+	"name":             {kind: kindString},
+	"description":      {kind: kindString},
+	"extends":          {kind: kindString},
+	"prompt-placement": {kind: kindEnum, enum: []string{PlacementAppend, PlacementPrepend, PlacementReplace}},
+	"response-schema":  {kind: kindSchema},
+	"vars":             {kind: kindObject, values: &field{kind: kindString, nullable: true}},
+	"template":         {kind: kindBool},
+	"tools":            {kind: kindList, items: &field{kind: kindString}},
+	"skills":           {kind: kindList, items: &field{kind: kindString}},
+	"disable-tools":    {kind: kindBool},
+}}
+
+// knownKey reports whether key (in either spelling) is in the schema.
+func knownKey(key string) bool {
+	_, ok := frontmatterSchema.fields[canonicalKey(key)]
+	return ok
+}
+
+// canonicalKey returns the hyphenated spelling of key.
+func canonicalKey(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// lineOffset converts frontmatter line numbers to file line numbers: the
+// YAML block starts after the opening "---".
+const lineOffset = 1
+
+// checkFrontmatter validates the frontmatter mapping node against the
+// schema.
+func checkFrontmatter(root *yaml.Node) []Issue {
+	if root.Kind != yaml.MappingNode {
+		return []Issue{nodeIssue(root, SeverityError, "frontmatter must be a mapping of keys to values")}
+	}
+	var issues []Issue
+	seen := map[string]string{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keyNode, valueNode := root.Content[i], root.Content[i+1]
+		key := keyNode.Value
+		canonical := canonicalKey(key)
+		if other, ok := seen[canonical]; ok {
+			issues = append(issues, nodeIssue(keyNode, SeverityWarning, fmt.Sprintf("%s duplicates %s; the later value wins", key, other)))
+		}
+		seen[canonical] = key
+
+		f, ok := frontmatterSchema.fields[canonical]
+		if !ok {
+			msg := unknownKeyMessage(key)
+			if valueNode.Kind != yaml.ScalarNode {
+				msg += " (ignored: not a single value)"
+			}
+			issues = append(issues, nodeIssue(keyNode, SeverityWarning, msg))
+			continue
+		}
+		issues = append(issues, checkValue(key, valueNode, f)...)
+	}
+	return issues
+}
+
+// checkValue validates one value; path names it in messages ("vars.lang",
+// "tools[1]").
+func checkValue(path string, n *yaml.Node, f *field) []Issue {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		if f.nullable {
+			return nil
+		}
+		return []Issue{nodeIssue(n, SeverityError, fmt.Sprintf("%s must have a value", path))}
+	}
+
+	switch f.kind {
+	case kindString:
+		if n.Kind != yaml.ScalarNode {
+			return []Issue{nodeIssue(n, SeverityError, fmt.Sprintf("%s must be a single value, got %s", path, describeNode(n)))}
+		}
+	case kindNumber, kindInteger:
+		want := "a number"
+		if f.kind == kindInteger {
+			want = "an integer"
+		}
+		if n.Kind != yaml.ScalarNode || (n.Tag != "!!int" && n.Tag != "!!float") || (f.kind == kindInteger && n.Tag != "!!int") {
+			return []Issue{nodeIssue(n, SeverityError, fmt.Sprintf("%s must be %s, got %s", path, want, describeNode(n)))}
+		}
+		v, err := strconv.ParseFloat(strings.ReplaceAll(n.Value, "_", ""), 64)
+		if err != nil || math.IsNaN(v) {
+			return []Issue{nodeIssue(n, SeverityError, fmt.Sprintf("%s must be %s, got %s", path, want, describeNode(n)))}
+		}
+		if (f.min != nil && v < *f.min) || (f.max != nil && v > *f.max) {
+			return []Issue{nodeIssue(n, SeverityError, fmt.Sprintf("%s must be %s, got %s", path, rangeString(f), n.Value))}
+		}
+	case kindBool:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!bool" {
+			return []Issue{nodeIssue(n, SeverityError, fmt.Sprintf("%s must be true or false, got %s", path, describeNode(n)))}
+		}
+	case kindEnum:
+		if n.Kind != yaml.ScalarNode || !contains(f.enum, n.Value) {
+			return []Issue{nodeIssue(n, SeverityError, fmt.Sprintf("%s must be one of %s, got %s", path, strings.Join(f.enum, ", "), describeNode(n)))}
+		}
+	case kindList:
+		if n.Kind != yaml.SequenceNode {
+			return []Issue{nodeIssue(n, SeverityError, fmt.Sprintf("%s must be a list, got %s", path, describeNode(n)))}
+		}
+		var issues []Issue
+		for i, item := range n.Content {
+			issues = append(issues, checkValue(fmt.Sprintf("%s[%d]", path, i), item, f.items)...)
+		}
+		return issues
+	case kindObject:
+		if n.Kind != yaml.MappingNode {
+			return []Issue{nodeIssue(n, SeverityError, fmt.Sprintf("%s must be a mapping, got %s", path, describeNode(n)))}
+		}
+		var issues []Issue
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			keyNode, valueNode := n.Content[i], n.Content[i+1]
+			sub := f.values
+			if f.fields != nil {
+				if sub = f.fields[canonicalKey(keyNode.Value)]; sub == nil {
+					issues = append(issues, nodeIssue(keyNode, SeverityWarning, fmt.Sprintf("unknown key %s.%s", path, keyNode.Value)))
+					continue
+				}
+			}
+			issues = append(issues, checkValue(path+"."+keyNode.Value, valueNode, sub)...)
+		}
+		return issues
+	case kindSchema:
+		if n.Kind != yaml.MappingNode && !(n.Kind == yaml.ScalarNode && n.Tag == "!!str" && strings.TrimSpace(n.Value) != "") {
+			return []Issue{nodeIssue(n, SeverityError, fmt.Sprintf("%s must be a file path or a mapping, got %s", path, describeNode(n)))}
+		}
+	}
+	return nil
+}
+
+func nodeIssue(n *yaml.Node, severity, msg string) Issue {
+	return Issue{Line: n.Line + lineOffset, Column: n.Column, Severity: severity, Message: msg}
+}
+
+// describeNode names a value for error messages.
+func describeNode(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return strconv.Quote(n.Value)
+}
+
+func rangeString(f *field) string {
+	switch {
+	case f.min != nil && f.max != nil:
+		return fmt.Sprintf("between %g and %g", *f.min, *f.max)
+	case f.min != nil:
+		return fmt.Sprintf("at least %g", *f.min)
+	}
+	return fmt.Sprintf("at most %g", *f.max)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownKeyMessage is the warning for an unknown key, with a suggestion
+// when the key looks like a typo of a known one.
+func unknownKeyMessage(key string) string {
+	msg := "unknown agent key: " + key
+	if s := suggestKey(key); s != "" {
+		msg += fmt.Sprintf(" (did you mean %s?)", s)
+	}
+	return msg
+}
+
+// suggestKey returns the known key closest to key, if it is within two
+// edits.
+func suggestKey(key string) string {
+	best, bestDist := "", 3
+	known := make([]string, 0, len(frontmatterSchema.fields))
+	for k := range frontmatterSchema.fields {
+		known = append(known, k)
+	}
+	sort.Strings(known)
+	for _, k := range known {
+		if d := editDistance(canonicalKey(strings.ToLower(key)), k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// issueErrors returns the error issues as one error, or nil.
+func issueErrors(issues []Issue) error {
+	var msgs []string
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			msgs = append(msgs, i.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid agent frontmatter: %s", strings.Join(msgs, "; "))
+}
+
+// yamlLinePattern extracts the line number from a yaml.v3 error.
+var yamlLinePattern = regexp.MustCompile(`line (\d+): (.*)`)
+
+// Lint checks the agent file at path without running it: frontmatter
+// syntax, the schema, unknown keys, and whether its extends chain loads.
+// Problems are returned as issues in file order; err is set only when the
+// file cannot be read.
+func Lint(path, baseDir string) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+	front, _, issues, err := parseFrontmatter(content)
+	if err != nil {
+		return []Issue{syntaxIssue(err)}, nil
+	}
+	if issueErrors(issues) == nil && lookupKey(front, "extends") != nil {
+		if _, err := Load(path, baseDir); err != nil {
+			issues = append(issues, Issue{Line: keyLine(content, "extends"), Column: 1, Severity: SeverityError, Message: err.Error()})
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues, nil
+}
+
+// syntaxIssue converts a frontmatter parse error into an issue, using the
+// line yaml.v3 reports when there is one.
+func syntaxIssue(err error) Issue {
+	issue := Issue{Line: 1, Severity: SeverityError, Message: err.Error()}
+	if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		issue.Line = n + lineOffset
+		issue.Message = "invalid agent frontmatter: " + m[2]
+	}
+	return issue
+}
+
+// keyLine returns the file line of a top-level frontmatter key, or 1.
+func keyLine(content, key string) int {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 1; i < len(lines) && strings.TrimSpace(lines[i]) != "---"; i++ {
+		if strings.HasPrefix(lines[i], key+":") {
+			return i + 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"run-ai/internal/agent"
+	"run-ai/internal/config"
+)
+
+// runAgents implements `rai agents list|show <name>` over the named agents
+// in .rai/agents/, and `rai agents lint <file|name>...`.
+func runAgents(p Parsed, stdout, stderr io.Writer, baseDir string) int {
+	args := p.SubArgs
+	switch {
+	case len(args) > 1 && args[0] == "lint":
+		return runAgentsLint(args[1:], p.StrictAgent, stdout, stderr, baseDir)
+	case len(args) == 1 && args[0] == "list":
+		discovered, warnings, err := agent.Discover(baseDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "agents error: %v\n", err)
+			return 1
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(stderr, "warning: %s\n", w)
+		}
+		fmt.Fprintln(stdout, agent.FormatList(discovered))
+		return 0
+	case len(args) == 2 && args[0] == "show":
+		ag, err := agent.Find(baseDir, args[1])
+		if err != nil {
+			fmt.Fprintf(stderr, "agents error: %v\n", err)
+			return 1
+		}
+		masked := make(map[string]string, len(ag.Config))
+		for key, value := range ag.Config {
+			if config.IsSecret(key) {
+				value = config.MaskSecret(value)
+			}
+			masked[key] = value
+		}
+		ag.Config = masked
+		fmt.Fprintln(stdout, agent.FormatShow(ag))
+		return 0
+	}
+	writeUsage(stderr)
+	return 2
+}
+
+// runAgentsLint checks each agent file against the frontmatter schema and
+// prints one path:line:column line per issue.  It fails on errors, and on
+// warnings too when strict is set.
+func runAgentsLint(refs []string, strict bool, stdout, stderr io.Writer, baseDir string) int {
+	var errs, warnings int
+	for _, ref := range refs {
+		path, err := agent.ResolvePath(baseDir, ref)
+		if err == nil {
+			_, err = os.Stat(path)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "agents error: %v\n", err)
+			errs++
+			continue
+		}
+		issues, err := agent.Lint(path, baseDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "agents error: %v\n", err)
+			errs++
+			continue
+		}
+		for _, i := range issues {
+			if i.Severity == agent.SeverityError {
+				errs++
+			} else {
+				warnings++
+			}
+			pos := fmt.Sprintf("%s:%d", path, i.Line)
+			if i.Column > 0 {
+				pos += fmt.Sprintf(":%d", i.Column)
+			}
+			fmt.Fprintf(stdout, "%s: %s: %s\n", pos, i.Severity, i.Message)
+		}
+	}
+	if errs == 0 && warnings == 0 {
+		fmt.Fprintf(stdout, "%d agent file(s) ok\n", len(refs))
+		return 0
+	}
+	fmt.Fprintf(stdout, "%d error(s), %d warning(s)\n", errs, warnings)
+	if errs > 0 || (strict && warnings > 0) {
+		return 1
+	}
+	return 0
+}
+
+// agentNames returns the names of the agents discovered under baseDir.
+func agentNames(baseDir string) []string {
+	discovered, _, err := agent.Discover(baseDir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(discovered))
+	for _, ag := range discovered {
+		names = append(names, ag.Name)
+	}
+	return names
+}
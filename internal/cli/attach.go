@@ -0,0 +1,473 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"run-ai/internal/config"
+	"run-ai/internal/provider"
+)
+
+// defaultAttachMaxBytes caps the combined size of --file attachments when
+// the attach-max-bytes config key is not set.
+const defaultAttachMaxBytes = 1 << 20
+
+// lineRangePattern matches the optional :start-end suffix of a --file spec.
+// Either bound may be omitted ("10-" or "-20"), and a single number selects
+// one line.
+var lineRangePattern = regexp.MustCompile(`:(\d*)-(\d*)$|:(\d+)$`)
+
+// attachment is one file embedded in the prompt.
+type attachment struct {
+	Path     string // as given (or matched), for display
+	Start    int    // first line, 1-based; 0 means from the start
+	End      int    // last line, inclusive; 0 means to the end
+	Language string
+	Content  string
+}
+
+// Label returns the path with its line range, e.g. "main.go:10-20".
+func (a attachment) Label() string {
+	if a.Start == 0 && a.End == 0 {
+		return a.Path
+	}
+	start, end := "", ""
+	if a.Start > 0 {
+		start = strconv.Itoa(a.Start)
+	}
+	if a.End > 0 {
+		end = strconv.Itoa(a.End)
+	}
+	return a.Path + ":" + start + "-" + end
+}
+
+// fileSpec is a parsed --file argument.
+type fileSpec struct {
+	Pattern    string
+	Start, End int
+}
+
+// parseFileSpec splits "path[:start-end]" into a pattern and line range.
+func parseFileSpec(spec string) (fileSpec, error) {
+	loc := lineRangePattern.FindStringIndex(spec)
+	if loc == nil {
+		return fileSpec{Pattern: spec}, nil
+	}
+	parsed := fileSpec{Pattern: spec[:loc[0]]}
+	rng := spec[loc[0]+1:]
+	start, end, isRange := strings.Cut(rng, "-")
+	if !isRange {
+		end = start
+	}
+	invalid := fmt.Errorf("invalid line range %q in --file %q (lines start at 1)", rng, spec)
+	if start == "" && end == "" {
+		return fileSpec{}, invalid
+	}
+	for _, bound := range []struct {
+		text string
+		dst  *int
+	}{{start, &parsed.Start}, {end, &parsed.End}} {
+		if bound.text == "" {
+			continue
+		}
+		n, err := strconv.Atoi(bound.text)
+		if err != nil || n < 1 {
+			return fileSpec{}, invalid
+		}
+		*bound.dst = n
+	}
+	if parsed.End > 0 && parsed.Start > parsed.End {
+		return fileSpec{}, invalid
+	}
+	return parsed, nil
+}
+
+// loadAttachments expands every --file spec, relative to baseDir, and reads
+// the files.  A literal path that is not UTF-8 text is an error; binary files
+// matched by a glob are skipped and reported in the returned warnings.
+func loadAttachments(specs []string, baseDir string, cfg map[string]string) ([]attachment, []string, error) {
+	if len(specs) == 0 {
+		return nil, nil, nil
+	}
+	limit, err := attachMaxBytes(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		out      []attachment
+		warnings []string
+		total    int64
+	)
+	seen := map[string]bool{}
+	for _, raw := range specs {
+		spec, err := parseFileSpec(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		paths := []string{spec.Pattern}
+		isGlob := hasGlobMeta(spec.Pattern)
+		if isGlob {
+			if paths, err = expandGlob(spec.Pattern, baseDir); err != nil {
+				return nil, nil, fmt.Errorf("--file %q: %w", raw, err)
+			}
+			if len(paths) == 0 {
+				return nil, nil, fmt.Errorf("--file %q matches no files", raw)
+			}
+		}
+		for _, path := range paths {
+			a := attachment{Path: path, Start: spec.Start, End: spec.End, Language: fileLanguage(path)}
+			if seen[a.Label()] {
+				continue
+			}
+			seen[a.Label()] = true
+
+			content, err := readAttachment(resolvePath(path, baseDir), spec, limit-total)
+			if err != nil {
+				if isGlob && errors.Is(err, errNotText) {
+					warnings = append(warnings, fmt.Sprintf("skipping binary file %s", path))
+					continue
+				}
+				if errors.Is(err, errAttachLimit) {
+					return nil, nil, fmt.Errorf("attached files exceed %d bytes (set attach-max-bytes to raise the limit)", limit)
+				}
+				return nil, nil, err
+			}
+			a.Content = content
+			total += int64(len(content))
+			out = append(out, a)
+		}
+	}
+	return out, warnings, nil
+}
+
+// attachMaxBytes returns the attach-max-bytes limit.
+func attachMaxBytes(cfg map[string]string) (int64, error) {
+	raw := strings.TrimSpace(config.Lookup(cfg, "attach-max-bytes"))
+	if raw == "" {
+		return defaultAttachMaxBytes, nil
+	}
+	limit, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("attach-max-bytes must be a positive integer, got %q", raw)
+	}
+	return limit, nil
+}
+
+// errAttachLimit is returned by readAttachment when the file (or its
+// selected lines) would take the attachments past attach-max-bytes.
+var errAttachLimit = errors.New("attachment limit exceeded")
+
+// readAttachment reads the file at path, or just the spec's line range, as
+// text.  Whole files are checked against the remaining budget before they are
+// read; line ranges are streamed, keeping only the selected lines.
+func readAttachment(path string, spec fileSpec, budget int64) (string, error) {
+	if spec.Start == 0 && spec.End == 0 {
+		if info, err := os.Stat(path); err == nil && info.Size() > budget {
+			return "", errAttachLimit
+		}
+		return readTextFile(path, "file")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("file: %w", err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil {
+		return "", fmt.Errorf("file: %w", err)
+	} else if info.IsDir() {
+		return "", fmt.Errorf("file %q is a directory", path)
+	}
+
+	start := spec.Start
+	if start == 0 {
+		start = 1
+	}
+	var (
+		buf   bytes.Buffer
+		lines int
+		eof   bool
+	)
+	r := bufio.NewReader(f)
+	for n := 1; spec.End == 0 || n <= spec.End; n++ {
+		// ReadSlice hands back long lines in pieces, so even a huge line
+		// outside the range is never held in memory.
+		for {
+			chunk, err := r.ReadSlice('\n')
+			if len(chunk) > 0 {
+				lines = n
+			}
+			if n >= start {
+				buf.Write(chunk)
+				if int64(buf.Len()) > budget {
+					return "", errAttachLimit
+				}
+			}
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err == io.EOF {
+				eof = true
+			} else if err != nil {
+				return "", fmt.Errorf("file: %w", err)
+			}
+			break
+		}
+		if eof {
+			break
+		}
+	}
+	if start > lines {
+		return "", fmt.Errorf("file %q: line %d is past the end of the file (%d lines)", path, start, lines)
+	}
+	data := buf.Bytes()
+	if err := validateText(data); err != nil {
+		return "", fmt.Errorf("file %q %w", path, err)
+	}
+	if eof {
+		return strings.TrimRight(string(data), "\n"), nil
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// formatAttachments renders attachments as delimited blocks to append to the
+// prompt, in the style of the stdin block.
+func formatAttachments(atts []attachment) string {
+	var b strings.Builder
+	for i, a := range atts {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "--- file: %s (%s) ---\n%s\n--- end file ---", a.Label(), a.Language, a.Content)
+	}
+	return b.String()
+}
+
+// withAttachments appends the attachment blocks to prompt.
+func withAttachments(prompt string, atts []attachment) string {
+	if len(atts) == 0 {
+		return prompt
+	}
+	if prompt == "" {
+		return formatAttachments(atts)
+	}
+	return prompt + "\n\n" + formatAttachments(atts)
+}
+
+// attachmentLabels lists the attachments for the log header.
+func attachmentLabels(atts []attachment) string {
+	labels := make([]string, len(atts))
+	for i, a := range atts {
+		labels[i] = a.Label()
+	}
+	return strings.Join(labels, ", ")
+}
+
+func resolvePath(path, baseDir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// expandGlob returns the regular files matching pattern, sorted.  Besides
+// filepath.Match syntax, a "**" path segment matches any number of
+// directories.  Paths are returned in the form of the pattern (relative
+// patterns yield paths relative to baseDir).
+func expandGlob(pattern, baseDir string) ([]string, error) {
+	pattern = filepath.ToSlash(pattern)
+	segments := strings.Split(pattern, "/")
+
+	// Walk from the longest literal directory prefix.
+	root := 0
+	for root < len(segments)-1 && !hasGlobMeta(segments[root]) {
+		root++
+	}
+	prefix := strings.Join(segments[:root], "/")
+	if prefix == "" && strings.HasPrefix(pattern, "/") {
+		prefix = "/"
+	}
+	rest := segments[root:]
+	for _, seg := range rest {
+		if _, err := filepath.Match(seg, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// Dot directories (.git, .rai, ...) are only searched when the pattern
+	// names one explicitly.
+	dotPattern := strings.Contains("/"+strings.Join(rest, "/"), "/.")
+
+	walkRoot := resolvePath(filepath.FromSlash(prefix), baseDir)
+	var matches []string
+	err := filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == walkRoot && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, _ := filepath.Rel(walkRoot, path)
+		if rel == "." {
+			return nil
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") && !dotPattern {
+				return filepath.SkipDir
+			}
+			if !matchPrefix(rest, parts) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && matchSegments(rest, parts) {
+			matches = append(matches, filepath.FromSlash(joinSlash(prefix, filepath.ToSlash(rel))))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func joinSlash(prefix, rel string) string {
+	switch prefix {
+	case "":
+		return rel
+	case "/":
+		return "/" + rel
+	}
+	return prefix + "/" + rel
+}
+
+// matchSegments reports whether path segments match pattern segments, where
+// "**" matches zero or more segments.
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], parts[1:])
+}
+
+// matchPrefix reports whether a directory's segments could lead to a match.
+func matchPrefix(pattern, parts []string) bool {
+	for i, part := range parts {
+		if i >= len(pattern) {
+			return false
+		}
+		if pattern[i] == "**" {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern[i], part); !ok {
+			return false
+		}
+	}
+	return len(parts) < len(pattern)
+}
+
+// fileLanguages maps file extensions to the language named in attachment
+// headers.
+var fileLanguages = map[string]string{
+	".c": "c", ".cc": "cpp", ".cpp": "cpp", ".cs": "csharp", ".css": "css",
+	".go": "go", ".h": "c", ".hpp": "cpp", ".html": "html", ".java": "java",
+	".js": "javascript", ".json": "json", ".jsx": "javascript", ".kt": "kotlin",
+	".lua": "lua", ".md": "markdown", ".php": "php", ".proto": "protobuf",
+	".py": "python", ".rb": "ruby", ".rs": "rust", ".scala": "scala",
+	".sh": "bash", ".sql": "sql", ".swift": "swift", ".toml": "toml",
+	".ts": "typescript", ".tsx": "typescript", ".txt": "text", ".xml": "xml",
+	".yaml": "yaml", ".yml": "yaml", ".zsh": "zsh",
+}
+
+// fileLanguage guesses a file's language from its name.
+func fileLanguage(path string) string {
+	base := filepath.Base(path)
+	switch base {
+	case "Dockerfile":
+		return "dockerfile"
+	case "Makefile", "GNUmakefile":
+		return "makefile"
+	}
+	if lang, ok := fileLanguages[strings.ToLower(filepath.Ext(base))]; ok {
+		return lang
+	}
+	return "text"
+}
+
+// maxImageBytes caps each --image file; providers reject larger inline
+// images anyway.
+const maxImageBytes = 10 << 20
+
+// imageTypes are the image formats accepted by every supported provider.
+var imageTypes = map[string]bool{
+	"image/gif":  true,
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// loadImages reads --image files, relative to baseDir, as base64 image parts.
+// The MIME type is sniffed from the content rather than the extension.
+func loadImages(paths []string, baseDir string) ([]provider.ContentPart, error) {
+	var parts []provider.ContentPart
+	for _, path := range paths {
+		full := resolvePath(path, baseDir)
+		info, err := os.Stat(full)
+		if err != nil {
+			return nil, fmt.Errorf("image: %w", err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("image %q is a directory", path)
+		}
+		if info.Size() > maxImageBytes {
+			return nil, fmt.Errorf("image %q exceeds %d bytes", path, maxImageBytes)
+		}
+		data, err := os.ReadFile(full)
+		if err != nil {
+			return nil, fmt.Errorf("image: %w", err)
+		}
+		mimeType := http.DetectContentType(data)
+		if !imageTypes[mimeType] {
+			return nil, fmt.Errorf("image %q is not a PNG, JPEG, GIF or WebP file (detected %s)", path, mimeType)
+		}
+		parts = append(parts, provider.ContentPart{
+			Type:     provider.PartImage,
+			MIMEType: mimeType,
+			Data:     base64.StdEncoding.EncodeToString(data),
+		})
+	}
+	return parts, nil
+}
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"run-ai/internal/output"
+	"run-ai/internal/session"
+)
+
+const (
+	chatPrompt         = "> "
+	chatContinuePrompt = "... "
+	chatBlockDelimiter = `"""`
+)
+
+// runChat starts an interactive multi-turn session.  The agent, skills and
+// provider are resolved once and reused for every turn; history is kept in a
+// session.Chat until the user resets or exits.
+func runChat(p Parsed, in io.Reader, stdout, stderr io.Writer, baseDir string) int {
+	if len(p.SubArgs) > 0 {
+		writeUsage(stderr)
+		return 2
+	}
+
+	// Chat output is always text: --output json does not apply.
+	p.JSON = false
+	s, ok := prepareSession(p, stdout, stderr, baseDir)
+	if !ok {
+		return 1
+	}
+	defer s.sink.Close()
+	sink, merged, rec := s.sink, s.merged, s.rec
+
+	s.headerArgs["mode"] = "chat"
+	s.writeHeader("(interactive chat)", stderr)
+
+	prov, err := resolveProvider(merged, p.LogLevel, sink, baseDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "provider error: %v\n", err)
+		return 1
+	}
+	cfg, ok := s.sessionConfig(prov, p, stderr, baseDir)
+	if !ok {
+		return 1
+	}
+	chat := session.NewChat(cfg)
+
+	// --file attachments and --image files are sent with the first message.
+	attachments, images := s.attachments, s.images
+
+	fmt.Fprintln(stdout, "rai chat — type /help for commands, /exit to quit")
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	ctx := context.Background()
+	for {
+		text, ok := readChatInput(scanner, stdout)
+		if !ok {
+			return 0
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "/") {
+			done, reset := runChatCommand(text, chat, merged, p, sink, stdout, stderr, baseDir)
+			if done {
+				return 0
+			}
+			if reset {
+				rec = session.NewRecord(time.Now())
+			}
+			continue
+		}
+
+		text = withAttachments(text, attachments)
+		sink.EmitLog(output.EventUser, text)
+		if err := chat.Send(ctx, text, images...); err != nil {
+			// Keep the attachments for the next message.
+			fmt.Fprintf(stderr, "session error: %v\n", err)
+			continue
+		}
+		attachments, images = nil, nil
+		saveRecord(rec, chat.History(), p, merged, stderr, baseDir)
+	}
+}
+
+// runChatCommand handles a slash command and reports whether the chat should
+// end and whether the conversation was reset.
+func runChatCommand(line string, chat *session.Chat, merged map[string]string, p Parsed, sink *output.Sink, stdout, stderr io.Writer, baseDir string) (done, reset bool) {
+	fields := strings.Fields(line)
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "/exit", "/quit":
+		return true, false
+	case "/help":
+		fmt.Fprintln(stdout, "Commands:")
+		fmt.Fprintln(stdout, "  /reset          clear the conversation history")
+		fmt.Fprintln(stdout, "  /model [name]   show or switch the model")
+		fmt.Fprintln(stdout, "  /save [path]    write the conversation to a JSON file")
+		fmt.Fprintln(stdout, "  /exit           leave the chat")
+		fmt.Fprintf(stdout, "End a line with \\ to continue it, or wrap multi-line input in %s.\n", chatBlockDelimiter)
+	case "/reset":
+		chat.Reset()
+		sink.EmitLog(output.EventUser, "/reset")
+		fmt.Fprintln(stdout, "conversation reset")
+		return false, true
+	case "/model":
+		if len(args) == 0 {
+			fmt.Fprintf(stdout, "model: %s\n", merged["model"])
+			return false, false
+		}
+		previous := merged["model"]
+		merged["model"] = args[0]
+		prov, err := resolveProvider(merged, p.LogLevel, sink, baseDir)
+		if err != nil {
+			merged["model"] = previous
+			fmt.Fprintf(stderr, "provider error: %v\n", err)
+			return false, false
+		}
+		chat.SetProvider(prov)
+		sink.EmitLog(output.EventUser, "/model "+args[0])
+		fmt.Fprintf(stdout, "model: %s\n", args[0])
+	case "/save":
+		path := filepath.Join(baseDir, ".rai", "chats", fmt.Sprintf("chat-%s.json", time.Now().Format("20060102.150405")))
+		if len(args) > 0 {
+			path = args[0]
+		}
+		if err := saveChat(path, chat); err != nil {
+			fmt.Fprintf(stderr, "save error: %v\n", err)
+			return false, false
+		}
+		fmt.Fprintf(stdout, "saved: %s\n", path)
+	default:
+		fmt.Fprintf(stderr, "unknown command: %s (try /help)\n", cmd)
+	}
+	return false, false
+}
+
+// readChatInput reads one user turn.  A line ending in a backslash continues
+// on the next line, and a line holding only """ opens a block that runs until
+// the closing """.  It returns false at end of input.
+func readChatInput(scanner *bufio.Scanner, stdout io.Writer) (string, bool) {
+	fmt.Fprint(stdout, chatPrompt)
+	if !scanner.Scan() {
+		fmt.Fprintln(stdout)
+		return "", false
+	}
+	line := scanner.Text()
+
+	if strings.TrimSpace(line) == chatBlockDelimiter {
+		var lines []string
+		for {
+			fmt.Fprint(stdout, chatContinuePrompt)
+			if !scanner.Scan() {
+				return strings.Join(lines, "\n"), len(lines) > 0
+			}
+			next := scanner.Text()
+			if strings.TrimSpace(next) == chatBlockDelimiter {
+				return strings.Join(lines, "\n"), true
+			}
+			lines = append(lines, next)
+		}
+	}
+
+	var lines []string
+	for strings.HasSuffix(line, "\\") {
+		lines = append(lines, strings.TrimSuffix(line, "\\"))
+		fmt.Fprint(stdout, chatContinuePrompt)
+		if !scanner.Scan() {
+			return strings.Join(lines, "\n"), true
+		}
+		line = scanner.Text()
+	}
+	lines = append(lines, line)
+	return strings.Join(lines, "\n"), true
+}
+
+// saveChat writes the conversation history as indented JSON.
+func saveChat(path string, chat *session.Chat) error {
+	data, err := json.MarshalIndent(chat.History(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -3,16 +3,23 @@ package cli
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"run-ai/internal/agent"
 	"run-ai/internal/config"
 	"run-ai/internal/output"
 	"run-ai/internal/provider"
+	"run-ai/internal/render"
+	"run-ai/internal/schema"
 	"run-ai/internal/session"
 	"run-ai/internal/skills"
 )
@@ -20,17 +27,114 @@ import (
 var copilotDeviceAuth = provider.DeviceAuth
 var copilotSaveToken = provider.SaveCopilotToken
 
+// stdinInput returns the process stdin and whether it is piped or redirected
+// (i.e. not an interactive terminal).  Tests override it.
+var stdinInput = func() (io.Reader, bool) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return os.Stdin, false
+	}
+	return os.Stdin, info.Mode()&os.ModeCharDevice == 0
+}
+
+// defaultStdinMaxBytes caps how much piped input is read when the
+// stdin-max-bytes config key is not set.
+const defaultStdinMaxBytes = 1 << 20
+
+// defaultSchemaRetries is how often a non-conforming structured answer is
+// sent back for correction when schema-retries is not set.
+const defaultSchemaRetries = 2
+
+// exitSchemaMismatch is the exit code when the final answer still fails
+// --schema validation after every retry, so scripts can tell it apart from
+// other failures (1) and usage errors (2).
+const exitSchemaMismatch = 3
+
 // Parsed holds parsed CLI arguments.
 type Parsed struct {
-	Command    string   // "config", "skills", "" (prompt mode)
-	SubArgs    []string // sub-command arguments
-	Prompt     string   // user prompt (prompt mode)
-	PromptPath string   // --prompt-file flag
-	AgentPath  string   // --agent flag
-	Silent     bool     // -silent flag
-	Log        bool     // -log flag
-	LogLevel   string   // optional: when -log is followed by a level (e.g. DEBUG)
-	ShowHelp   bool     // -h / --help / help
+	Command     string            // "config", "skills", "chat", "completion", ... or "" (prompt mode)
+	SubArgs     []string          // sub-command arguments
+	Prompt      string            // user prompt (prompt mode)
+	PromptPath  string            // --prompt-file flag ("-" reads stdin)
+	AgentPath   string            // --agent flag
+	StrictAgent bool              // --strict-agent: agent warnings are errors
+	Silent      bool              // -silent flag
+	Log         bool              // -log flag
+	LogLevel    string            // optional: when -log is followed by a level (e.g. DEBUG)
+	Continue    bool              // --continue: append to the most recent saved session
+	ResumeID    string            // --resume flag: append to the saved session with this ID
+	ShowHelp    bool              // -h / --help / help
+	JSON        bool              // --output json: console events as NDJSON
+	SchemaPath  string            // --schema flag: JSON Schema the final answer must match
+	Vars        map[string]string // --var name=value: template variables
+	Files       []string          // --file path[:start-end] specs, in order
+	Images      []string          // --image paths, in order
+
+	// Overrides holds config values from --model, --provider, --endpoint,
+	// --temperature, --max-tokens and --set key=value.  They form the cli
+	// layer, the highest config precedence.
+	Overrides map[string]string
+	ArgError  string // first malformed flag, reported as a usage error
+}
+
+// overrideFlags maps dedicated override flags to the config keys they set.
+var overrideFlags = map[string]string{
+	"--model":       "model",
+	"--provider":    "provider",
+	"--endpoint":    "endpoint",
+	"--temperature": "temperature",
+	"--max-tokens":  "max-tokens",
+}
+
+// setOutput handles one --output argument.
+func (p *Parsed) setOutput(format string) {
+	switch strings.ToLower(format) {
+	case "json":
+		p.JSON = true
+	case "text":
+		p.JSON = false
+	default:
+		if p.ArgError == "" {
+			p.ArgError = fmt.Sprintf("--output expects text or json, got %q", format)
+		}
+	}
+}
+
+// parseVar handles one --var argument of the form name=value.
+func (p *Parsed) parseVar(arg string) {
+	name, value, ok := strings.Cut(arg, "=")
+	name = strings.TrimSpace(name)
+	if !ok || !render.ValidName(name) {
+		if p.ArgError == "" {
+			p.ArgError = fmt.Sprintf("--var expects name=value with a name of letters, digits and underscores, got %q", arg)
+		}
+		return
+	}
+	if p.Vars == nil {
+		p.Vars = map[string]string{}
+	}
+	p.Vars[name] = value
+}
+
+// setOverride records a cli-layer config value.
+func (p *Parsed) setOverride(key, value string) {
+	if p.Overrides == nil {
+		p.Overrides = map[string]string{}
+	}
+	p.Overrides[key] = value
+}
+
+// parseSet handles one --set argument of the form key=value.
+func (p *Parsed) parseSet(arg string) {
+	key, value, ok := strings.Cut(arg, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		if p.ArgError == "" {
+			p.ArgError = fmt.Sprintf("--set expects key=value, got %q", arg)
+		}
+		return
+	}
+	p.setOverride(key, value)
 }
 
 // ParseArgs separates flags from positional arguments.
@@ -39,6 +143,26 @@ func ParseArgs(args []string) Parsed {
 	var positional []string
 
 	for i := 0; i < len(args); i++ {
+		// Everything after `skills run <name> <script>` belongs to the
+		// script and is passed through verbatim.
+		if isSkillRun(positional) {
+			positional = append(positional, args[i:]...)
+			break
+		}
+		if key, ok := overrideFlags[args[i]]; ok {
+			if i+1 < len(args) {
+				i++
+				p.setOverride(key, args[i])
+			}
+			continue
+		}
+		if name, value, ok := strings.Cut(args[i], "="); ok {
+			if key, ok := overrideFlags[name]; ok {
+				p.setOverride(key, value)
+				continue
+			}
+		}
+
 		switch args[i] {
 		case "-h", "--help", "help":
 			p.ShowHelp = true
@@ -62,11 +186,64 @@ func ParseArgs(args []string) Parsed {
 				i++
 				p.AgentPath = args[i]
 			}
+		case "--set":
+			if i+1 < len(args) {
+				i++
+				p.parseSet(args[i])
+			}
+		case "--var":
+			if i+1 < len(args) {
+				i++
+				p.parseVar(args[i])
+			}
+		case "--file":
+			if i+1 < len(args) {
+				i++
+				p.Files = append(p.Files, args[i])
+			}
+		case "--image":
+			if i+1 < len(args) {
+				i++
+				p.Images = append(p.Images, args[i])
+			}
+		case "--schema":
+			if i+1 < len(args) {
+				i++
+				p.SchemaPath = args[i]
+			}
+		case "--output":
+			if i+1 < len(args) {
+				i++
+				p.setOutput(args[i])
+			}
+		case "--strict-agent":
+			p.StrictAgent = true
+		case "--continue":
+			p.Continue = true
+		case "--resume":
+			if i+1 < len(args) {
+				i++
+				p.ResumeID = args[i]
+			}
 		default:
 			if strings.HasPrefix(args[i], "--agent=") {
 				p.AgentPath = strings.TrimPrefix(args[i], "--agent=")
 			} else if strings.HasPrefix(args[i], "--prompt-file=") {
 				p.PromptPath = strings.TrimPrefix(args[i], "--prompt-file=")
+			} else if strings.HasPrefix(args[i], "--set=") {
+				p.parseSet(strings.TrimPrefix(args[i], "--set="))
+			} else if strings.HasPrefix(args[i], "--var=") {
+				p.parseVar(strings.TrimPrefix(args[i], "--var="))
+			} else if strings.HasPrefix(args[i], "--file=") {
+				p.Files = append(p.Files, strings.TrimPrefix(args[i], "--file="))
+			} else if strings.HasPrefix(args[i], "--image=") {
+				p.Images = append(p.Images, strings.TrimPrefix(args[i], "--image="))
+			} else if strings.HasPrefix(args[i], "--schema=") {
+				p.SchemaPath = strings.TrimPrefix(args[i], "--schema=")
+			} else if strings.HasPrefix(args[i], "--output=") {
+				p.setOutput(strings.TrimPrefix(args[i], "--output="))
+			} else if strings.HasPrefix(args[i], "--resume=") {
+				p.ResumeID = strings.TrimPrefix(args[i], "--resume=")
 			} else {
 				positional = append(positional, args[i])
 			}
@@ -84,18 +261,54 @@ func ParseArgs(args []string) Parsed {
 	case "skills":
 		p.Command = "skills"
 		p.SubArgs = positional[1:]
+	case "agents":
+		p.Command = "agents"
+		p.SubArgs = positional[1:]
 	case "copilot-login":
 		p.Command = "copilot-login"
 		p.SubArgs = positional[1:]
+	case "chat":
+		p.Command = "chat"
+		p.SubArgs = positional[1:]
+	case "sessions":
+		p.Command = "sessions"
+		p.SubArgs = positional[1:]
+	case "completion":
+		p.Command = "completion"
+		p.SubArgs = positional[1:]
+	case "models":
+		p.Command = "models"
+		p.SubArgs = positional[1:]
+	case "doctor":
+		p.Command = "doctor"
+		p.SubArgs = positional[1:]
 	default:
 		p.Prompt = strings.TrimSpace(strings.Join(positional, " "))
 	}
 	return p
 }
 
+// isSkillRun reports whether positional is a complete `skills run <name>
+// <script>` prefix.
+func isSkillRun(positional []string) bool {
+	return len(positional) == 4 && positional[0] == "skills" && positional[1] == "run"
+}
+
 // Run executes the CLI command and returns an exit code.
 func Run(args []string, stdout, stderr io.Writer, baseDir string) int {
-	if len(args) == 0 {
+	// Completion callbacks pass partial words through verbatim; they must not
+	// be interpreted as flags.
+	if len(args) > 0 && args[0] == completeCommand {
+		return runComplete(args[1:], stdout, baseDir)
+	}
+
+	in, piped := stdinInput()
+	var stdin io.Reader
+	if piped {
+		stdin = in
+	}
+
+	if len(args) == 0 && stdin == nil {
 		writeUsage(stderr)
 		return 2
 	}
@@ -106,106 +319,400 @@ func Run(args []string, stdout, stderr io.Writer, baseDir string) int {
 		writeUsage(stdout)
 		return 0
 	}
+	if parsed.ArgError != "" {
+		fmt.Fprintf(stderr, "argument error: %s\n", parsed.ArgError)
+		return 2
+	}
+	if parsed.Continue && parsed.ResumeID != "" {
+		fmt.Fprintln(stderr, "session error: use either --continue or --resume, not both")
+		return 2
+	}
+
+	// --agent accepts a name from .rai/agents/ as well as a path.
+	if parsed.Command == "" || parsed.Command == "chat" {
+		path, err := agent.ResolvePath(baseDir, parsed.AgentPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "agent error: %v\n", err)
+			return 1
+		}
+		parsed.AgentPath = path
+	}
 
 	switch parsed.Command {
 	case "config":
-		return runConfig(parsed.SubArgs, stdout, stderr, baseDir)
+		return runConfig(parsed, stdout, stderr, baseDir)
 	case "skills":
 		return runSkills(parsed.SubArgs, stdout, stderr, baseDir)
+	case "agents":
+		return runAgents(parsed, stdout, stderr, baseDir)
 	case "copilot-login":
 		return runCopilotLogin(parsed.SubArgs, stdout, stderr, baseDir)
+	case "chat":
+		return runChat(parsed, in, stdout, stderr, baseDir)
+	case "sessions":
+		return runSessions(parsed.SubArgs, stdout, stderr, baseDir)
+	case "completion":
+		return runCompletion(parsed.SubArgs, stdout, stderr)
+	case "models":
+		return runModels(parsed, stdout, stderr, baseDir)
+	case "doctor":
+		return runDoctor(parsed, stdout, stderr, baseDir)
 	default:
 		if parsed.Prompt != "" && parsed.PromptPath != "" {
 			fmt.Fprintln(stderr, "prompt error: provide either a prompt string or --prompt-file, not both")
 			return 2
 		}
-		if parsed.Prompt == "" && parsed.PromptPath == "" {
+		if parsed.Prompt == "" && parsed.PromptPath == "" && stdin == nil {
 			writeUsage(stderr)
 			return 2
 		}
-		return runPrompt(parsed, stdout, stderr, baseDir)
+		return runPrompt(parsed, stdin, stdout, stderr, baseDir)
 	}
 }
 
-// runPrompt handles the prompt command with output sink, optional agent, and logging.
-func runPrompt(p Parsed, stdout, stderr io.Writer, baseDir string) int {
-	if p.PromptPath != "" {
-		prompt, err := loadPromptFile(p.PromptPath)
-		if err != nil {
-			fmt.Fprintf(stderr, "prompt error: %v\n", err)
-			return 1
-		}
-		p.Prompt = prompt
-	}
+// preparedSession holds the setup runPrompt and runChat share: the open
+// sink, the rendered agent, merged config, --file and --image inputs, the
+// session record and the common log header arguments.
+type preparedSession struct {
+	sink        *output.Sink
+	ag          agent.Agent
+	merged      map[string]string
+	genOpts     provider.GenerationOptions
+	vars        map[string]string
+	attachments []attachment
+	images      []provider.ContentPart
+	rec         session.Record
+	headerArgs  map[string]string
+}
 
+// prepareSession opens the sink, loads and renders the agent, merges the
+// config (defaults < env < file < agent < cli), reads attachments and
+// images and opens the session record.  Failures are reported on stderr and
+// return false; on success the caller closes the sink.
+func prepareSession(p Parsed, stdout, stderr io.Writer, baseDir string) (*preparedSession, bool) {
 	sink, err := output.NewSink(output.Options{
 		Silent:  p.Silent,
+		JSON:    p.JSON,
 		Log:     p.Log,
 		BaseDir: baseDir,
 		Console: stdout,
 	})
 	if err != nil {
 		fmt.Fprintf(stderr, "output error: %v\n", err)
+		return nil, false
+	}
+	s := &preparedSession{sink: sink}
+	if !s.load(p, stderr, baseDir) {
+		sink.Close()
+		return nil, false
+	}
+	return s, true
+}
+
+// load does the work of prepareSession once the sink is open.
+func (s *preparedSession) load(p Parsed, stderr io.Writer, baseDir string) bool {
+	var err error
+	if s.ag, err = loadAgent(p, baseDir, s.sink); err != nil {
+		fmt.Fprintf(stderr, "agent error: %v\n", err)
+		return false
+	}
+	if s.merged, err = config.LoadMerged(baseDir, s.ag.Config, cliOverrides(p), map[string]string{}); err != nil {
+		fmt.Fprintf(stderr, "config error: %v\n", err)
+		return false
+	}
+	if s.genOpts, err = provider.ParseGenerationOptions(s.merged); err != nil {
+		fmt.Fprintf(stderr, "config error: %v\n", err)
+		return false
+	}
+
+	s.vars = templateVars(p, s.ag, s.merged, baseDir)
+	includes, err := renderAgent(p, &s.ag, s.vars, s.merged, baseDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "agent error: %v\n", err)
+		return false
+	}
+
+	attachments, warnings, err := loadAttachments(p.Files, baseDir, s.merged)
+	if err != nil {
+		fmt.Fprintf(stderr, "file error: %v\n", err)
+		return false
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+	s.attachments = attachments
+	if s.images, err = loadImages(p.Images, baseDir); err != nil {
+		fmt.Fprintf(stderr, "image error: %v\n", err)
+		return false
+	}
+
+	if s.rec, err = openRecord(p, baseDir); err != nil {
+		fmt.Fprintf(stderr, "session error: %v\n", err)
+		return false
+	}
+
+	s.headerArgs = buildHeaderArgs(p)
+	if len(s.rec.Messages) > 0 {
+		s.headerArgs["continue"] = s.rec.ID
+	}
+	if len(s.attachments) > 0 {
+		s.headerArgs["files"] = attachmentLabels(s.attachments)
+	}
+	if len(p.Images) > 0 {
+		s.headerArgs["images"] = strings.Join(p.Images, ", ")
+	}
+	if len(includes) > 0 {
+		s.headerArgs["includes"] = strings.Join(includes, ", ")
+	}
+	return true
+}
+
+// writeHeader writes the log header with prompt as the user prompt and
+// tells the user where the log file is being written.
+func (s *preparedSession) writeHeader(prompt string, stderr io.Writer) {
+	s.sink.WriteHeader(s.headerArgs, s.ag.SystemPrompt, prompt)
+	if logPath := s.sink.LogPath(); logPath != "" {
+		fmt.Fprintf(stderr, "log: %s\n", logPath)
+	}
+}
+
+// sessionConfig checks the images against prov and returns the session
+// configuration both modes start from, with the agent's skills and the
+// delegate tool.
+func (s *preparedSession) sessionConfig(prov provider.Provider, p Parsed, stderr io.Writer, baseDir string) (session.Config, bool) {
+	if len(s.images) > 0 {
+		if err := checkImageSupport(prov, s.merged, baseDir, stderr); err != nil {
+			fmt.Fprintf(stderr, "image error: %v\n", err)
+			return session.Config{}, false
+		}
+	}
+	discovered, err := agentSkills(s.ag, baseDir, s.sink)
+	if err != nil {
+		fmt.Fprintf(stderr, "agent error: %v\n", err)
+		return session.Config{}, false
+	}
+	delegate, err := newDelegate(p, s.merged, baseDir, s.sink)
+	if err != nil {
+		fmt.Fprintf(stderr, "config error: %v\n", err)
+		return session.Config{}, false
+	}
+	return session.Config{
+		Provider:     prov,
+		Sink:         s.sink,
+		SystemPrompt: s.ag.SystemPrompt,
+		Skills:       discovered,
+		Tools:        s.ag.Tools,
+		DisableTools: s.ag.DisableTools,
+		BaseDir:      baseDir,
+		Options:      s.genOpts,
+		History:      s.rec.Messages,
+		Delegate:     delegate,
+	}, true
+}
+
+// runPrompt handles the prompt command with output sink, optional agent, and logging.
+// stdin is non-nil when input was piped into rai; its content is read as the
+// prompt (or appended to the positional prompt).
+func runPrompt(p Parsed, stdin io.Reader, stdout, stderr io.Writer, baseDir string) int {
+	s, ok := prepareSession(p, stdout, stderr, baseDir)
+	if !ok {
 		return 1
 	}
-	defer sink.Close()
+	defer s.sink.Close()
 
-	// Load agent if specified.
-	var ag agent.Agent
-	if p.AgentPath != "" {
-		ag, err = agent.ParseFile(p.AgentPath)
-		if err != nil {
-			fmt.Fprintf(stderr, "agent error: %v\n", err)
+	respSchema, err := loadResponseSchema(p, s.ag)
+	if err != nil {
+		fmt.Fprintf(stderr, "schema error: %v\n", err)
+		return 1
+	}
+	retries, err := schemaRetries(s.merged)
+	if err != nil {
+		fmt.Fprintf(stderr, "config error: %v\n", err)
+		return 1
+	}
+
+	prompt, err := resolvePrompt(p, stdin, s.merged)
+	if err != nil {
+		fmt.Fprintf(stderr, "prompt error: %v\n", err)
+		return 1
+	}
+	if p.PromptPath != "" && p.PromptPath != "-" && templating(p, s.ag) {
+		if prompt, err = render.Render(filepath.Base(p.PromptPath), prompt, s.vars, nil); err != nil {
+			fmt.Fprintf(stderr, "prompt error: %v\n", err)
 			return 1
 		}
-		for _, w := range ag.Warnings {
-			sink.Emit(output.EventERR, w)
+	}
+	p.Prompt = withAttachments(prompt, s.attachments)
+
+	if p.PromptPath != "" {
+		s.headerArgs["prompt-file"] = p.PromptPath
+	}
+	if stdin != nil && p.PromptPath != "-" {
+		s.headerArgs["stdin"] = "true"
+	}
+	if p.SchemaPath != "" {
+		s.headerArgs["schema"] = p.SchemaPath
+	}
+	s.writeHeader(p.Prompt, stderr)
+
+	prov, err := resolveProvider(s.merged, p.LogLevel, s.sink, baseDir)
+	if err != nil {
+		// No provider configured — fall back to echo mode for basic usage.
+		fmt.Fprintf(stderr, "warning: no provider (%v); echoing the prompt, run 'rai doctor' to diagnose\n", err)
+		s.sink.EmitFinal(fmt.Sprintf("prompt: %s", p.Prompt))
+		return 0
+	}
+	cfg, ok := s.sessionConfig(prov, p, stderr, baseDir)
+	if !ok {
+		return 1
+	}
+	cfg.UserPrompt = p.Prompt
+	cfg.Images = s.images
+	cfg.ResponseSchema = respSchema
+	cfg.SchemaRetries = retries
+
+	messages, err := session.RunConversation(context.Background(), cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "session error: %v\n", err)
+		var schemaErr *session.SchemaError
+		if errors.As(err, &schemaErr) {
+			return exitSchemaMismatch
 		}
+		return 1
 	}
 
-	// Build log header arguments.
-	headerArgs := map[string]string{}
+	saveRecord(s.rec, messages, p, s.merged, stderr, baseDir)
+	return 0
+}
+
+// openRecord returns the saved session selected by --continue/--resume, or a
+// fresh record when neither flag is given.
+func openRecord(p Parsed, baseDir string) (session.Record, error) {
+	switch {
+	case p.Continue:
+		return session.LatestRecord(baseDir)
+	case p.ResumeID != "":
+		return session.LoadRecord(baseDir, p.ResumeID)
+	default:
+		return session.NewRecord(time.Now()), nil
+	}
+}
+
+// saveRecord persists the conversation so it can be continued later.  Failure
+// to save is reported but does not fail the run: the response was delivered.
+// The session ID is printed on stderr except under -silent and --output json,
+// which promise nothing but the response (or events) on the console.
+func saveRecord(rec session.Record, messages []provider.Message, p Parsed, merged map[string]string, stderr io.Writer, baseDir string) {
+	rec.Messages = messages
+	rec.Updated = time.Now()
 	if p.AgentPath != "" {
-		headerArgs["agent"] = p.AgentPath
+		rec.Agent = p.AgentPath
 	}
-	if p.PromptPath != "" {
-		headerArgs["prompt-file"] = p.PromptPath
+	if model := merged["model"]; model != "" {
+		rec.Model = model
+	}
+	if err := session.SaveRecord(baseDir, rec); err != nil {
+		fmt.Fprintf(stderr, "warning: saving session: %v\n", err)
+		return
+	}
+	if !p.Silent && !p.JSON {
+		fmt.Fprintf(stderr, "session: %s\n", rec.ID)
+	}
+}
+
+// loadAgent loads the --agent file, if any, and reports its warnings through
+// the sink, or fails on them with --strict-agent.  No --agent yields a zero
+// Agent.
+func loadAgent(p Parsed, baseDir string, sink *output.Sink) (agent.Agent, error) {
+	if p.AgentPath == "" {
+		return agent.Agent{}, nil
+	}
+	ag, err := agent.Load(p.AgentPath, baseDir)
+	if err != nil {
+		return agent.Agent{}, err
+	}
+	if p.StrictAgent && len(ag.Warnings) > 0 {
+		return agent.Agent{}, fmt.Errorf("%s: %s (--strict-agent treats warnings as errors)", p.AgentPath, strings.Join(ag.Warnings, "; "))
+	}
+	for _, w := range ag.Warnings {
+		sink.Emit(output.EventERR, w)
+	}
+	return ag, nil
+}
+
+// agentSkills discovers the skills in baseDir, narrowed to the agent's
+// skills list.  Agents with disable-tools get none.
+func agentSkills(ag agent.Agent, baseDir string, sink *output.Sink) ([]skills.Skill, error) {
+	if ag.DisableTools {
+		return nil, nil
+	}
+	discovered, issues, _ := skills.Discover(baseDir)
+	for _, i := range issues {
+		sink.Emit(output.EventERR, i.String())
+	}
+	if ag.Skills == nil {
+		return discovered, nil
+	}
+	return skills.Select(discovered, ag.Skills)
+}
+
+// buildHeaderArgs returns the log header arguments shared by every session mode.
+func buildHeaderArgs(p Parsed) map[string]string {
+	args := map[string]string{}
+	if p.AgentPath != "" {
+		args["agent"] = p.AgentPath
 	}
 	if p.Silent {
-		headerArgs["silent"] = "true"
+		args["silent"] = "true"
 	}
 	if p.Log {
-		headerArgs["log"] = "true"
+		args["log"] = "true"
 	}
 	if p.LogLevel != "" {
-		headerArgs["log-level"] = p.LogLevel
+		args["log-level"] = p.LogLevel
 	}
-
-	sink.WriteHeader(headerArgs, ag.SystemPrompt, p.Prompt)
-
-	// Inform user where the log file is being written.
-	if logPath := sink.LogPath(); logPath != "" {
-		fmt.Fprintf(stderr, "log: %s\n", logPath)
+	if p.JSON {
+		args["output"] = "json"
+	}
+	for name, value := range p.Vars {
+		args["var."+name] = value
+	}
+	for key, value := range p.Overrides {
+		if config.IsSecret(key) {
+			value = config.MaskSecret(value)
+		}
+		args[key] = value
 	}
+	return args
+}
 
-	// Merge configuration: defaults < env < file < agent < cli.
-	defaults := map[string]string{}
-	merged, err := config.LoadMerged(baseDir, ag.Config, map[string]string{}, defaults)
-	if err != nil {
-		fmt.Fprintf(stderr, "config error: %v\n", err)
-		return 1
+// cliOverrides returns the cli config layer, never nil.
+func cliOverrides(p Parsed) map[string]string {
+	if p.Overrides == nil {
+		return map[string]string{}
 	}
+	return p.Overrides
+}
 
+// resolveProvider prepares merged config for provider construction (debug
+// hooks, stored Copilot token) and resolves the provider.
+func resolveProvider(merged map[string]string, logLevel string, sink *output.Sink, baseDir string) (provider.Provider, error) {
 	// Internal-only debug hooks: allow providers to append raw HTTP JSON bodies
 	// to the active session log when `-log DEBUG` is used.
-	if strings.EqualFold(p.LogLevel, "DEBUG") {
+	if strings.EqualFold(logLevel, "DEBUG") {
 		if lp := sink.LogPath(); lp != "" {
 			merged["_log_level"] = "DEBUG"
 			merged["_log_path"] = lp
 		}
 	}
 
-	// Load stored Copilot token when provider is github-copilot and no key yet.
+	loadCopilotToken(merged, baseDir)
+	return provider.Resolve(merged)
+}
+
+// loadCopilotToken fills api-key from the stored Copilot token when the
+// provider is github-copilot and no key is configured.
+func loadCopilotToken(merged map[string]string, baseDir string) {
 	provID := merged["provider"]
 	if (provID == "github-copilot" || provID == "github-copilot-enterprise") &&
 		merged["api-key"] == "" && merged["api_key"] == "" {
@@ -213,39 +720,61 @@ func runPrompt(p Parsed, stdout, stderr io.Writer, baseDir string) int {
 			merged["api-key"] = tok
 		}
 	}
+}
 
-	// Resolve provider.
-	prov, err := provider.Resolve(merged)
-	if err != nil {
-		// No provider configured — fall back to echo mode for basic usage.
-		sink.EmitFinal(fmt.Sprintf("prompt: %s", p.Prompt))
-		return 0
+// runConfig implements `rai config`: set (the default two-argument form),
+// get, list and unset.
+func runConfig(p Parsed, stdout, stderr io.Writer, baseDir string) int {
+	var args []string
+	var resolved, showSecrets bool
+	for _, arg := range p.SubArgs {
+		switch arg {
+		case "--resolved":
+			resolved = true
+		case "--show-secrets":
+			showSecrets = true
+		default:
+			args = append(args, arg)
+		}
 	}
-
-	// Discover skills.
-	discovered, skillWarnings, _ := skills.Discover(baseDir)
-	for _, w := range skillWarnings {
-		sink.Emit(output.EventERR, w)
+	if len(args) == 0 {
+		writeUsage(stderr)
+		return 2
 	}
 
-	// Run the session.
-	ctx := context.Background()
-	if err := session.Run(ctx, session.Config{
-		Provider:     prov,
-		Sink:         sink,
-		SystemPrompt: ag.SystemPrompt,
-		UserPrompt:   p.Prompt,
-		Skills:       discovered,
-		BaseDir:      baseDir,
-	}); err != nil {
-		fmt.Fprintf(stderr, "session error: %v\n", err)
-		return 1
+	switch args[0] {
+	case "get":
+		if len(args) != 2 {
+			writeUsage(stderr)
+			return 2
+		}
+		return runConfigGet(p, args[1], showSecrets, stdout, stderr, baseDir)
+	case "list":
+		if len(args) != 1 {
+			writeUsage(stderr)
+			return 2
+		}
+		return runConfigList(p, resolved, showSecrets, stdout, stderr, baseDir)
+	case "unset":
+		if len(args) != 2 {
+			writeUsage(stderr)
+			return 2
+		}
+		found, err := config.Unset(baseDir, strings.TrimSpace(args[1]))
+		if err != nil {
+			fmt.Fprintf(stderr, "config error: %v\n", err)
+			return 1
+		}
+		if !found {
+			fmt.Fprintf(stderr, "config error: %s is not set in %s\n", args[1], config.ConfigPath(baseDir))
+			return 1
+		}
+		fmt.Fprintln(stdout, "config updated")
+		return 0
+	case "set":
+		args = args[1:]
 	}
 
-	return 0
-}
-
-func runConfig(args []string, stdout, stderr io.Writer, baseDir string) int {
 	if len(args) != 2 {
 		writeUsage(stderr)
 		return 2
@@ -271,25 +800,95 @@ func runConfig(args []string, stdout, stderr io.Writer, baseDir string) int {
 	return 0
 }
 
-func runSkills(args []string, stdout, stderr io.Writer, baseDir string) int {
-	if len(args) == 0 || args[0] != "list" {
-		writeUsage(stderr)
-		return 2
-	}
-
-	discovered, warnings, err := skills.Discover(baseDir)
+// runConfigGet prints the effective value of key across all config layers.
+func runConfigGet(p Parsed, key string, showSecrets bool, stdout, stderr io.Writer, baseDir string) int {
+	values, err := resolvedConfig(p, baseDir)
 	if err != nil {
-		fmt.Fprintf(stderr, "skills error: %v\n", err)
+		fmt.Fprintf(stderr, "config error: %v\n", err)
 		return 1
 	}
-	for _, w := range warnings {
-		fmt.Fprintf(stderr, "warning: %s\n", w)
+	v, ok := values[key]
+	if !ok {
+		v, ok = values[strings.ReplaceAll(key, "-", "_")]
+	}
+	if !ok {
+		fmt.Fprintf(stderr, "config error: %s is not set\n", key)
+		return 1
+	}
+	fmt.Fprintln(stdout, displayConfigValue(key, v.Value, showSecrets))
+	return 0
+}
+
+// runConfigList prints .rai/config, or with resolved every merged value and
+// the layer (env, file, agent, cli) it came from.
+func runConfigList(p Parsed, resolved, showSecrets bool, stdout, stderr io.Writer, baseDir string) int {
+	var values map[string]config.Resolved
+	if resolved {
+		var err error
+		values, err = resolvedConfig(p, baseDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "config error: %v\n", err)
+			return 1
+		}
+	} else {
+		fileValues, err := config.Load(baseDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "config error: %v\n", err)
+			return 1
+		}
+		values = config.MergeSources(nil, nil, fileValues, nil, nil)
 	}
 
-	fmt.Fprintln(stdout, skills.FormatList(discovered))
+	if len(values) == 0 {
+		fmt.Fprintln(stdout, "no config values set")
+		return 0
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		v := values[key]
+		line := fmt.Sprintf("%s = %s", key, displayConfigValue(key, v.Value, showSecrets))
+		if resolved {
+			line += fmt.Sprintf("  (%s)", v.Source)
+		}
+		fmt.Fprintln(stdout, line)
+	}
 	return 0
 }
 
+// resolvedConfig merges every layer the prompt command would use, including
+// the --agent file and override flags given alongside `rai config`.
+func resolvedConfig(p Parsed, baseDir string) (map[string]config.Resolved, error) {
+	fileValues, err := config.Load(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	var agentValues map[string]string
+	if p.AgentPath != "" {
+		path, err := agent.ResolvePath(baseDir, p.AgentPath)
+		if err != nil {
+			return nil, fmt.Errorf("agent: %w", err)
+		}
+		ag, err := agent.Load(path, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("agent: %w", err)
+		}
+		agentValues = ag.Config
+	}
+	return config.MergeSources(nil, config.EnvValues(), fileValues, agentValues, p.Overrides), nil
+}
+
+func displayConfigValue(key, value string, showSecrets bool) string {
+	if !showSecrets && config.IsSecret(key) {
+		return config.MaskSecret(value)
+	}
+	return value
+}
+
 func runCopilotLogin(args []string, stdout, stderr io.Writer, baseDir string) int {
 	domain := "github.com"
 	if len(args) > 0 {
@@ -357,32 +956,241 @@ func authenticateCopilot(domain string, stdout, stderr io.Writer, baseDir string
 func writeUsage(writer io.Writer) {
 	fmt.Fprintln(writer, "Usage:")
 	fmt.Fprintln(writer, "  rai <prompt>")
-	fmt.Fprintln(writer, "  rai --agent <file> <prompt>")
-	fmt.Fprintln(writer, "  rai --prompt-file <file>")
+	fmt.Fprintln(writer, "  rai --agent <file|name> <prompt>")
+	fmt.Fprintln(writer, "  rai --prompt-file <file|->")
+	fmt.Fprintln(writer, "  <command> | rai [prompt]")
 	fmt.Fprintln(writer, "  rai -silent <prompt>")
 	fmt.Fprintln(writer, "  rai -log <prompt>")
-	fmt.Fprintln(writer, "  rai config <key> <value>")
+	fmt.Fprintln(writer, "  rai --output json <prompt>")
+	fmt.Fprintln(writer, "  rai --schema schema.json <prompt>")
+	fmt.Fprintln(writer, "  rai --agent a.md --var name=value <prompt>")
+	fmt.Fprintln(writer, "  rai --agent a.md --strict-agent <prompt>")
+	fmt.Fprintln(writer, "  rai --file 'src/**/*.go' --file main.go:10-40 <prompt>")
+	fmt.Fprintln(writer, "  rai --image screenshot.png <prompt>")
+	fmt.Fprintln(writer, "  rai --model <name> [--provider <id>] [--endpoint <url>] <prompt>")
+	fmt.Fprintln(writer, "  rai --temperature <n> --max-tokens <n> <prompt>")
+	fmt.Fprintln(writer, "  rai --set <key>=<value> <prompt>")
+	fmt.Fprintln(writer, "  rai --continue <prompt>")
+	fmt.Fprintln(writer, "  rai --resume <id> <prompt>")
+	fmt.Fprintln(writer, "  rai chat [--agent <file|name>]")
+	fmt.Fprintln(writer, "  rai config [set] <key> <value>")
+	fmt.Fprintln(writer, "  rai config get <key> [--show-secrets]")
+	fmt.Fprintln(writer, "  rai config list [--resolved] [--show-secrets]")
+	fmt.Fprintln(writer, "  rai config unset <key>")
+	fmt.Fprintln(writer, "  rai sessions list|show <id>|delete <id>")
 	fmt.Fprintln(writer, "  rai skills list")
+	fmt.Fprintln(writer, "  rai skills validate [--json]")
+	fmt.Fprintln(writer, "  rai skills show <name>")
+	fmt.Fprintln(writer, "  rai skills run <name> <script> [args...] [--json-args '{...}']")
+	fmt.Fprintln(writer, "  rai agents list|show <name>")
+	fmt.Fprintln(writer, "  rai agents lint [--strict-agent] <file|name>...")
 	fmt.Fprintln(writer, "  rai copilot-login [domain]")
+	fmt.Fprintln(writer, "  rai models [--json] [--refresh]")
+	fmt.Fprintln(writer, "  rai doctor [--json] [--no-request]")
+	fmt.Fprintln(writer, "  rai completion bash|zsh|fish")
+}
+
+// resolvePrompt builds the final user prompt from the positional prompt,
+// --prompt-file, and piped stdin.  When both stdin and a positional prompt are
+// present the piped content is appended in a delimited block so the model can
+// tell instructions apart from input data.
+func resolvePrompt(p Parsed, stdin io.Reader, cfg map[string]string) (string, error) {
+	limit, err := stdinMaxBytes(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	if p.PromptPath == "-" {
+		if stdin == nil {
+			stdin, _ = stdinInput()
+		}
+		return readStdin(stdin, limit)
+	}
+	if p.PromptPath != "" {
+		return loadPromptFile(p.PromptPath)
+	}
+	if stdin == nil {
+		return p.Prompt, nil
+	}
+
+	piped, err := readStdin(stdin, limit)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case p.Prompt == "":
+		return piped, nil
+	case piped == "":
+		return p.Prompt, nil
+	default:
+		return fmt.Sprintf("%s\n\n--- stdin ---\n%s\n--- end stdin ---", p.Prompt, piped), nil
+	}
+}
+
+func stdinMaxBytes(cfg map[string]string) (int64, error) {
+	raw := strings.TrimSpace(config.Lookup(cfg, "stdin-max-bytes"))
+	if raw == "" {
+		return defaultStdinMaxBytes, nil
+	}
+	limit, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("stdin-max-bytes must be a positive integer, got %q", raw)
+	}
+	return limit, nil
+}
+
+// readStdin reads at most limit bytes from r and applies the same text
+// validation as prompt files.
+func readStdin(r io.Reader, limit int64) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("reading stdin: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return "", fmt.Errorf("stdin exceeds %d bytes; raise the limit with 'rai config stdin-max-bytes <n>'", limit)
+	}
+	if err := validateText(data); err != nil {
+		return "", fmt.Errorf("stdin %w", err)
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// errNotText is returned by validateText for binary content.
+var errNotText = errors.New("is not valid UTF-8 text")
+
+// validateText rejects binary content: NUL bytes or invalid UTF-8.
+func validateText(data []byte) error {
+	if len(data) > 0 && (bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)) {
+		return errNotText
+	}
+	return nil
+}
+
+// templateVars returns the variables for agent and prompt-file templates:
+// built-ins, then agent frontmatter defaults, then --var values.
+func templateVars(p Parsed, ag agent.Agent, merged map[string]string, baseDir string) map[string]string {
+	return render.Merge(render.Builtins(baseDir, merged["model"], time.Now()), ag.Vars, p.Vars)
+}
+
+// renderAgent renders the agent's system prompt in place, expanding its
+// include directives, and returns the included files for the log header.
+// Includes may not leave the workspace or the directories of the agent file
+// and the parents it extends.
+func renderAgent(p Parsed, ag *agent.Agent, vars, merged map[string]string, baseDir string) ([]string, error) {
+	limit, err := includeMaxBytes(merged)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	inc := &render.Includer{Dir: root, Roots: []string{root}, MaxBytes: limit}
+	if ag.Path != "" {
+		dir, err := filepath.Abs(filepath.Dir(ag.Path))
+		if err != nil {
+			return nil, err
+		}
+		inc.Dir = dir
+		inc.Roots = append(inc.Roots, dir)
+	}
+	inc.Roots = append(inc.Roots, ag.Dirs...)
+	if templating(p, *ag) {
+		ag.SystemPrompt, err = render.Render(agentTemplateName(p), ag.SystemPrompt, vars, inc)
+	} else {
+		ag.SystemPrompt, err = inc.Expand(ag.SystemPrompt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inc.Files, nil
+}
+
+// templating reports whether the agent body and prompt file are rendered as
+// templates: when the agent asks for it or any --var is given.  Otherwise
+// their {{...}} text is kept verbatim.
+func templating(p Parsed, ag agent.Agent) bool {
+	return ag.Template || len(p.Vars) > 0
+}
+
+// includeMaxBytes returns the include-max-bytes limit.
+func includeMaxBytes(cfg map[string]string) (int64, error) {
+	raw := strings.TrimSpace(config.Lookup(cfg, "include-max-bytes"))
+	if raw == "" {
+		return render.DefaultIncludeMaxBytes, nil
+	}
+	limit, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("include-max-bytes must be a positive integer, got %q", raw)
+	}
+	return limit, nil
+}
+
+// agentTemplateName names the agent body in template errors.
+func agentTemplateName(p Parsed) string {
+	if p.AgentPath == "" {
+		return "agent"
+	}
+	return filepath.Base(p.AgentPath)
+}
+
+// loadResponseSchema returns the schema from --schema, or else from the
+// agent's response-schema frontmatter (inline, or a path relative to the
+// agent file).  It returns nil when neither is set.
+func loadResponseSchema(p Parsed, ag agent.Agent) (*schema.Schema, error) {
+	if p.SchemaPath != "" {
+		return schema.Load(p.SchemaPath)
+	}
+	if ag.ResponseSchema == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(ag.ResponseSchema, "{") {
+		return schema.Parse([]byte(ag.ResponseSchema))
+	}
+	path := ag.ResponseSchema
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(p.AgentPath), path)
+	}
+	return schema.Load(path)
+}
+
+// schemaRetries returns how many times a non-conforming answer is sent back
+// for correction (schema-retries, default 2).
+func schemaRetries(cfg map[string]string) (int, error) {
+	raw := strings.TrimSpace(config.Lookup(cfg, "schema-retries"))
+	if raw == "" {
+		return defaultSchemaRetries, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("schema-retries must be a non-negative integer, got %q", raw)
+	}
+	return n, nil
 }
 
 func loadPromptFile(path string) (string, error) {
+	return readTextFile(path, "prompt file")
+}
+
+// readTextFile reads a UTF-8 text file, naming it label in errors.  Trailing
+// newlines are dropped.
+func readTextFile(path, label string) (string, error) {
 	if strings.TrimSpace(path) == "" {
-		return "", fmt.Errorf("prompt file path is empty")
+		return "", fmt.Errorf("%s path is empty", label)
 	}
 	info, err := os.Stat(path)
 	if err != nil {
-		return "", fmt.Errorf("prompt file: %w", err)
+		return "", fmt.Errorf("%s: %w", label, err)
 	}
 	if info.IsDir() {
-		return "", fmt.Errorf("prompt file %q is a directory", path)
+		return "", fmt.Errorf("%s %q is a directory", label, path)
 	}
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return "", fmt.Errorf("prompt file: %w", err)
+		return "", fmt.Errorf("%s: %w", label, err)
 	}
-	if len(data) > 0 && (bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)) {
-		return "", fmt.Errorf("prompt file %q is not valid UTF-8 text", path)
+	if err := validateText(data); err != nil {
+		return "", fmt.Errorf("%s %q %w", label, path, err)
 	}
 	return strings.TrimRight(string(data), "\n"), nil
 }
@@ -1,18 +1,43 @@
 package cli
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
+	"run-ai/internal/agent"
+	"run-ai/internal/config"
+	"run-ai/internal/output"
 	"run-ai/internal/provider"
+	"run-ai/internal/session"
 )
 
+// Tests run without piped stdin unless a test opts in via withStdin, so that
+// `go test < file` cannot change the outcome of unrelated tests.
+func init() {
+	stdinInput = func() (io.Reader, bool) { return nil, false }
+}
+
+func withStdin(t *testing.T, content string) {
+	t.Helper()
+	prev := stdinInput
+	stdinInput = func() (io.Reader, bool) { return strings.NewReader(content), true }
+	t.Cleanup(func() { stdinInput = prev })
+}
+
 // --- ParseArgs tests ---
 
 func TestParseArgsPrompt(t *testing.T) {
@@ -312,6 +337,51 @@ func TestRunWithAgentWarnings(t *testing.T) {
 	}
 }
 
+func TestRunStrictAgentFailsOnWarnings(t *testing.T) {
+	dir := t.TempDir()
+	agentPath := filepath.Join(dir, "agent.md")
+	os.WriteFile(agentPath, []byte("---\ntemprature: 0.2\n---\nSystem prompt.\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"--agent", agentPath, "--strict-agent", "query"}, &stdout, &stderr, dir)
+	if code != 1 || !strings.Contains(stderr.String(), "did you mean temperature?") || !strings.Contains(stderr.String(), "--strict-agent") {
+		t.Fatalf("code = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestRunAgentsLint(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.md")
+	os.WriteFile(good, []byte("---\nmodel: gpt-4\n---\nBody\n"), 0o644)
+	warn := filepath.Join(dir, "warn.md")
+	os.WriteFile(warn, []byte("---\nflavor: x\n---\nBody\n"), 0o644)
+	bad := filepath.Join(dir, "bad.md")
+	os.WriteFile(bad, []byte("---\nmodel: gpt-4\ntemperature: hot\n---\nBody\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"agents", "lint", good}, &stdout, &stderr, dir); code != 0 || !strings.Contains(stdout.String(), "1 agent file(s) ok") {
+		t.Fatalf("good: code = %d, stdout = %q, stderr = %q", code, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"agents", "lint", good, warn}, &stdout, &stderr, dir); code != 0 || !strings.Contains(stdout.String(), warn+":2:1: warning: unknown agent key: flavor") {
+		t.Fatalf("warn: code = %d, stdout = %q", code, stdout.String())
+	}
+	if code := Run([]string{"agents", "lint", "--strict-agent", warn}, &stdout, &stderr, dir); code != 1 {
+		t.Fatalf("strict warn: code = %d", code)
+	}
+
+	stdout.Reset()
+	code := Run([]string{"agents", "lint", bad, warn}, &stdout, &stderr, dir)
+	if code != 1 || !strings.Contains(stdout.String(), bad+`:3:14: error: temperature must be a number, got "hot"`) || !strings.Contains(stdout.String(), "1 error(s), 1 warning(s)") {
+		t.Fatalf("bad: code = %d, stdout = %q", code, stdout.String())
+	}
+
+	if code := Run([]string{"agents", "lint"}, &stdout, &stderr, dir); code != 2 {
+		t.Fatalf("no files: code = %d, want 2", code)
+	}
+}
+
 func TestRunWithMissingAgent(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	code := Run([]string{"--agent", "/does/not/exist.md", "query"}, &stdout, &stderr, t.TempDir())
@@ -358,6 +428,154 @@ func TestRunSkillsListWithSkills(t *testing.T) {
 	}
 }
 
+func TestRunSkillsValidate(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"good":    "---\nname: good\ndescription: Fine.\n---\n",
+		"Bad-One": "---\nname: Bad-One\ndescription: Odd name.\n---\n",
+		"broken":  "---\nname: broken\n---\n",
+	} {
+		skillDir := filepath.Join(dir, ".rai", "skills", name)
+		os.MkdirAll(skillDir, 0o755)
+		os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte(content), 0o644)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"skills", "validate"}, &stdout, &stderr, dir)
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	for _, want := range []string{
+		".rai/skills/Bad-One/SKILL.md: warning: name \"Bad-One\" should be lowercase",
+		".rai/skills/broken/SKILL.md: error: SKILL.md missing required 'description' field",
+		"1 error(s), 1 warning(s)",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("missing %q in output:\n%s", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	code = Run([]string{"skills", "validate", "--json"}, &stdout, &stderr, dir)
+	var report struct {
+		OK     bool
+		Skills []string
+		Issues []struct{ Skill, Path, Severity, Message string }
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout.String(), err)
+	}
+	if code != 1 || report.OK || strings.Join(report.Skills, ",") != "Bad-One,good" {
+		t.Fatalf("code = %d, report = %+v", code, report)
+	}
+	if len(report.Issues) != 2 || report.Issues[0].Severity != "warning" || report.Issues[1].Skill != "broken" || report.Issues[1].Severity != "error" {
+		t.Fatalf("issues = %+v", report.Issues)
+	}
+
+	// Warnings alone do not fail validation.
+	os.RemoveAll(filepath.Join(dir, ".rai", "skills", "broken"))
+	stdout.Reset()
+	if code := Run([]string{"skills", "validate"}, &stdout, &stderr, dir); code != 0 || !strings.Contains(stdout.String(), "0 error(s), 1 warning(s)") {
+		t.Fatalf("code = %d, output = %q", code, stdout.String())
+	}
+
+	os.RemoveAll(filepath.Join(dir, ".rai", "skills", "Bad-One"))
+	stdout.Reset()
+	if code := Run([]string{"skills", "validate"}, &stdout, &stderr, dir); code != 0 || stdout.String() != "1 skill(s) ok\n" {
+		t.Fatalf("code = %d, output = %q", code, stdout.String())
+	}
+}
+
+func TestRunSkillsShowAndRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	dir := t.TempDir()
+	skillDir := filepath.Join(dir, ".rai", "skills", "greet")
+	os.MkdirAll(filepath.Join(skillDir, "scripts"), 0o755)
+	os.MkdirAll(filepath.Join(skillDir, "references"), 0o755)
+	os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: greet\ndescription: Greets.\nlicense: MIT\n---\nRun scripts/hello.sh NAME.\n"), 0o644)
+	os.WriteFile(filepath.Join(skillDir, "scripts", "hello.sh"), []byte("#!/bin/sh\necho \"hello $1 from $(pwd)\"\necho note >&2\nexit 3\n"), 0o755)
+	os.WriteFile(filepath.Join(skillDir, "references", "names.md"), []byte("x"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"skills", "show", "greet"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("show: code = %d, stderr = %q", code, stderr.String())
+	}
+	for _, want := range []string{"name: greet\n", "license: MIT\n", "scripts:\n  scripts/hello.sh\n", "resources:\n  references/names.md\n", "\nRun scripts/hello.sh NAME."} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("show output missing %q:\n%s", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code := Run([]string{"skills", "run", "greet", "hello.sh", "world"}, &stdout, &stderr, dir)
+	root, _ := filepath.EvalSymlinks(dir)
+	if code != 3 || strings.TrimSpace(stdout.String()) != "hello world from "+root || stderr.String() != "note\n" {
+		t.Fatalf("run: code = %d, stdout = %q, stderr = %q", code, stdout.String(), stderr.String())
+	}
+
+	stderr.Reset()
+	if code := Run([]string{"skills", "run", "greet", "missing.sh"}, io.Discard, &stderr, dir); code != 2 || !strings.Contains(stderr.String(), "invalid skill arguments") {
+		t.Fatalf("unknown script: code = %d, stderr = %q", code, stderr.String())
+	}
+	stderr.Reset()
+	if code := Run([]string{"skills", "show", "nope"}, io.Discard, &stderr, dir); code != 1 || !strings.Contains(stderr.String(), `skill "nope" not found`) {
+		t.Fatalf("unknown skill: code = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestRunSkillsRunPassesArgsVerbatim(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	dir := t.TempDir()
+	skillDir := filepath.Join(dir, ".rai", "skills", "echo")
+	os.MkdirAll(filepath.Join(skillDir, "scripts"), 0o755)
+	os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: echo\ndescription: Echoes.\n---\n"), 0o644)
+	os.WriteFile(filepath.Join(skillDir, "scripts", "run.sh"), []byte("#!/bin/sh\nprintf '[%s]' \"$@\"\n"), 0o755)
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"a", "--model", "gpt", "-x", "help"}, "[a][--model][gpt][-x][help]"},
+		{[]string{"--file", "x.txt", "-silent", "b"}, "[--file][x.txt][-silent][b]"},
+		{[]string{"--", "--model", "--json-args"}, "[--model][--json-args]"},
+	} {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"--model", "outer", "skills", "run", "echo", "run.sh"}, tt.args...)
+		if code := Run(args, &stdout, &stderr, dir); code != 0 || stdout.String() != tt.want {
+			t.Errorf("run %q: code = %d, stdout = %q, stderr = %q", tt.args, code, stdout.String(), stderr.String())
+		}
+	}
+}
+
+func TestRunSkillsRunJSONArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	dir := t.TempDir()
+	skillDir := filepath.Join(dir, ".rai", "skills", "echo")
+	os.MkdirAll(filepath.Join(skillDir, "scripts"), 0o755)
+	os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: echo\ndescription: Echoes.\nparameters:\n  type: object\n  properties:\n    word: {type: string}\n  required: [word]\n---\n"), 0o644)
+	os.WriteFile(filepath.Join(skillDir, "scripts", "run.sh"), []byte("#!/bin/sh\ncat\n"), 0o755)
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"skills", "run", "echo", "run.sh", "--json-args", `{"word":"hi"}`}, &stdout, &stderr, dir); code != 0 || stdout.String() != `{"word":"hi"}` {
+		t.Fatalf("code = %d, stdout = %q, stderr = %q", code, stdout.String(), stderr.String())
+	}
+	stderr.Reset()
+	if code := Run([]string{"skills", "run", "echo", "run.sh", `--json-args={}`}, io.Discard, &stderr, dir); code != 2 || !strings.Contains(stderr.String(), `missing required property "word"`) {
+		t.Fatalf("code = %d, stderr = %q", code, stderr.String())
+	}
+	stderr.Reset()
+	if code := Run([]string{"skills", "run", "echo", "run.sh", "hi"}, io.Discard, &stderr, dir); code != 2 || !strings.Contains(stderr.String(), "--json-args") {
+		t.Fatalf("code = %d, stderr = %q", code, stderr.String())
+	}
+}
+
 func TestRunLogWithAgent(t *testing.T) {
 	dir := t.TempDir()
 
@@ -387,3 +605,1274 @@ func TestRunLogWithAgent(t *testing.T) {
 		t.Fatalf("expected prompt in log")
 	}
 }
+
+// --- Stdin tests ---
+
+func TestRunStdinOnly(t *testing.T) {
+	withStdin(t, "piped question\n")
+	var stdout, stderr bytes.Buffer
+	code := Run(nil, &stdout, &stderr, t.TempDir())
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0 (stderr %q)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "prompt: piped question") {
+		t.Fatalf("expected stdin prompt echo, got %q", stdout.String())
+	}
+}
+
+func TestRunStdinWithPrompt(t *testing.T) {
+	withStdin(t, "diff --git a/x b/x\n")
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"review this"}, &stdout, &stderr, t.TempDir())
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	want := "prompt: review this\n\n--- stdin ---\ndiff --git a/x b/x\n--- end stdin ---"
+	if !strings.Contains(stdout.String(), want) {
+		t.Fatalf("expected delimited stdin block, got %q", stdout.String())
+	}
+}
+
+func TestRunPromptFileDash(t *testing.T) {
+	withStdin(t, "from stdin")
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"--prompt-file", "-"}, &stdout, &stderr, t.TempDir())
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	if !strings.Contains(stdout.String(), "prompt: from stdin") {
+		t.Fatalf("expected stdin prompt echo, got %q", stdout.String())
+	}
+	if strings.Contains(stdout.String(), "--- stdin ---") {
+		t.Fatalf("--prompt-file - should not wrap content, got %q", stdout.String())
+	}
+}
+
+func TestRunStdinRejectsBinary(t *testing.T) {
+	withStdin(t, "bad\x00data")
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"review"}, &stdout, &stderr, t.TempDir())
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "not valid UTF-8") {
+		t.Fatalf("expected validation error, got %q", stderr.String())
+	}
+}
+
+func TestRunStdinSizeCap(t *testing.T) {
+	dir := t.TempDir()
+	withStdin(t, strings.Repeat("a", 32))
+	t.Setenv("RAI_STDIN_MAX_BYTES", "16")
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"review"}, &stdout, &stderr, dir)
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "exceeds 16 bytes") {
+		t.Fatalf("expected size cap error, got %q", stderr.String())
+	}
+}
+
+// --- Chat tests ---
+
+// writeMockProviderConfig points .rai/config at an OpenAI-compatible mock
+// server that answers every request with reply.
+func writeMockProviderConfig(t *testing.T, dir string, reply func(n int) string) {
+	t.Helper()
+	var n int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"type\":\"response.output_text.delta\",\"delta\":%q}\n", reply(n))
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	}))
+	t.Cleanup(srv.Close)
+	for k, v := range map[string]string{"endpoint": srv.URL, "api-key": "test", "model": "test-model"} {
+		if err := config.Set(dir, k, v); err != nil {
+			t.Fatalf("config: %v", err)
+		}
+	}
+}
+
+func TestRunAgentUnknownSkill(t *testing.T) {
+	dir := t.TempDir()
+	writeMockProviderConfig(t, dir, func(n int) string { return "ok" })
+	agentPath := filepath.Join(dir, "agent.md")
+	os.WriteFile(agentPath, []byte("---\nskills: [missing]\n---\nBody\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"--agent", agentPath, "hi"}, &stdout, &stderr, dir)
+	if code != 1 || !strings.Contains(stderr.String(), `skill "missing" not found`) {
+		t.Fatalf("code = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestParseArgsChat(t *testing.T) {
+	p := ParseArgs([]string{"chat", "--agent", "a.md"})
+	if p.Command != "chat" || p.AgentPath != "a.md" {
+		t.Fatalf("unexpected parse: %+v", p)
+	}
+}
+
+func TestRunChatTurnsAndCommands(t *testing.T) {
+	dir := t.TempDir()
+	writeMockProviderConfig(t, dir, func(n int) string { return fmt.Sprintf("reply %d", n) })
+	savePath := filepath.Join(dir, "chat.json")
+	withStdin(t, "hello\n/model other-model\nline one \\\nline two\n/save "+savePath+"\n/bogus\n/exit\n")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"chat"}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0 (stderr %q)", code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{"[AI] reply 1", "model: other-model", "[AI] reply 2", "saved: " + savePath} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+	if !strings.Contains(stderr.String(), "unknown command: /bogus") {
+		t.Errorf("expected unknown command error, got %q", stderr.String())
+	}
+
+	data, err := os.ReadFile(savePath)
+	if err != nil {
+		t.Fatalf("reading saved chat: %v", err)
+	}
+	var history []provider.Message
+	if err := json.Unmarshal(data, &history); err != nil {
+		t.Fatalf("saved chat is not JSON: %v", err)
+	}
+	if len(history) != 4 || history[2].Content != "line one \nline two" {
+		t.Fatalf("unexpected saved history: %+v", history)
+	}
+}
+
+func TestRunChatReset(t *testing.T) {
+	dir := t.TempDir()
+	writeMockProviderConfig(t, dir, func(n int) string { return "ok" })
+	savePath := filepath.Join(dir, "chat.json")
+	withStdin(t, "hello\n/reset\n/save "+savePath+"\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"chat"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	data, _ := os.ReadFile(savePath)
+	if strings.TrimSpace(string(data)) != "[]" {
+		t.Fatalf("expected empty history after reset, got %s", data)
+	}
+
+	// Any spelling that resets the chat starts a new saved session.
+	dir = t.TempDir()
+	writeMockProviderConfig(t, dir, func(n int) string { return "ok" })
+	withStdin(t, "hello\n/reset now\nagain\n")
+	if code := Run([]string{"chat"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	records, err := session.ListRecords(dir)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("expected two sessions, got %d (%v)", len(records), err)
+	}
+}
+
+func TestRunChatKeepsAttachmentsAfterFailedSend(t *testing.T) {
+	dir := t.TempDir()
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) == 1 {
+			http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"ok"}`)
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	}))
+	defer srv.Close()
+	for k, v := range map[string]string{"endpoint": srv.URL, "api-key": "test", "model": "test-model"} {
+		config.Set(dir, k, v)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("the notes"), 0o644)
+	withStdin(t, "first\nsecond\nthird\n/exit\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"chat", "--file", "notes.txt"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	if len(bodies) != 3 || !strings.Contains(stderr.String(), "session error") {
+		t.Fatalf("requests = %d, stderr = %q", len(bodies), stderr.String())
+	}
+	if !strings.Contains(bodies[1], "the notes") {
+		t.Errorf("attachment not resent after failure: %s", bodies[1])
+	}
+	if strings.Count(bodies[2], "the notes") != 1 {
+		t.Errorf("attachment should be sent once it succeeded: %s", bodies[2])
+	}
+}
+
+func TestReadChatInputBlock(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("\"\"\"\nfirst\n\nsecond\n\"\"\"\nnext\n"))
+	var out bytes.Buffer
+	text, ok := readChatInput(scanner, &out)
+	if !ok || text != "first\n\nsecond" {
+		t.Fatalf("block input = %q, %v", text, ok)
+	}
+	text, ok = readChatInput(scanner, &out)
+	if !ok || text != "next" {
+		t.Fatalf("next input = %q, %v", text, ok)
+	}
+	if _, ok := readChatInput(scanner, &out); ok {
+		t.Fatal("expected end of input")
+	}
+}
+
+// --- Session persistence tests ---
+
+func TestParseArgsContinueResume(t *testing.T) {
+	p := ParseArgs([]string{"--continue", "more"})
+	if !p.Continue || p.Prompt != "more" {
+		t.Fatalf("unexpected parse: %+v", p)
+	}
+	p = ParseArgs([]string{"--resume=abc", "more"})
+	if p.ResumeID != "abc" || p.Prompt != "more" {
+		t.Fatalf("unexpected parse: %+v", p)
+	}
+}
+
+func TestRunContinueAppendsTurn(t *testing.T) {
+	dir := t.TempDir()
+	writeMockProviderConfig(t, dir, func(n int) string { return fmt.Sprintf("answer %d", n) })
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"first question"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d, want 0 (stderr %q)", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "session: ") {
+		t.Fatalf("expected session id on stderr, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := Run([]string{"-silent", "--continue", "follow up"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d, want 0 (stderr %q)", code, stderr.String())
+	}
+	if stderr.Len() > 0 {
+		t.Fatalf("-silent should not print the session id, got %q", stderr.String())
+	}
+
+	records, err := session.ListRecords(dir)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("expected one session, got %d (%v)", len(records), err)
+	}
+	msgs := records[0].Messages
+	if len(msgs) != 4 || msgs[0].Content != "first question" || msgs[2].Content != "follow up" || msgs[3].Content != "answer 2" {
+		t.Fatalf("unexpected history: %+v", msgs)
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"sessions", "show", records[0].ID}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("sessions show exit code = %d", code)
+	}
+	if !strings.Contains(stdout.String(), "--- user ---\nfollow up") {
+		t.Fatalf("expected transcript, got %q", stdout.String())
+	}
+}
+
+func TestRunContinueWithoutSessions(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"--continue", "more"}, &stdout, &stderr, t.TempDir())
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "no saved sessions") {
+		t.Fatalf("expected no sessions error, got %q", stderr.String())
+	}
+}
+
+func TestRunSessionsListAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"sessions", "list"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d", code)
+	}
+	if !strings.Contains(stdout.String(), "no sessions found") {
+		t.Fatalf("expected empty listing, got %q", stdout.String())
+	}
+
+	rec := session.NewRecord(time.Now())
+	rec.Messages = []provider.Message{{Role: "user", Content: "what is go"}}
+	if err := session.SaveRecord(dir, rec); err != nil {
+		t.Fatalf("SaveRecord: %v", err)
+	}
+
+	stdout.Reset()
+	Run([]string{"sessions", "list"}, &stdout, &stderr, dir)
+	if !strings.Contains(stdout.String(), rec.ID) || !strings.Contains(stdout.String(), "what is go") {
+		t.Fatalf("expected session in listing, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"sessions", "delete", rec.ID}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("delete exit code = %d", code)
+	}
+	if code := Run([]string{"sessions", "delete", rec.ID}, &stdout, &stderr, dir); code != 1 {
+		t.Fatalf("second delete exit code = %d, want 1", code)
+	}
+}
+
+func TestFirstUserPromptTruncatesByRune(t *testing.T) {
+	prompt := strings.Repeat("é", 80)
+	got := firstUserPrompt(session.Record{Messages: []provider.Message{{Role: "user", Content: prompt}}})
+	if !utf8.ValidString(got) {
+		t.Fatalf("summary is not valid UTF-8: %q", got)
+	}
+	if want := strings.Repeat("é", 69) + "..."; got != want {
+		t.Fatalf("summary = %q, want %q", got, want)
+	}
+}
+
+// --- Config override flag tests ---
+
+func TestParseArgsOverrides(t *testing.T) {
+	p := ParseArgs([]string{"--model", "gpt-x", "--temperature=0.2", "--max-tokens", "50", "--set", "top-p=0.9", "--set=reasoning-summary=off", "--provider", "github-copilot", "--endpoint=http://e", "hi"})
+	want := map[string]string{
+		"model":             "gpt-x",
+		"temperature":       "0.2",
+		"max-tokens":        "50",
+		"top-p":             "0.9",
+		"reasoning-summary": "off",
+		"provider":          "github-copilot",
+		"endpoint":          "http://e",
+	}
+	for k, v := range want {
+		if p.Overrides[k] != v {
+			t.Errorf("Overrides[%q] = %q, want %q", k, p.Overrides[k], v)
+		}
+	}
+	if p.Prompt != "hi" {
+		t.Fatalf("prompt = %q, want hi", p.Prompt)
+	}
+}
+
+func TestRunSetRequiresKeyValue(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"--set", "novalue", "hi"}, &stdout, &stderr, t.TempDir())
+	if code != 2 {
+		t.Fatalf("exit code = %d, want 2", code)
+	}
+	if !strings.Contains(stderr.String(), "--set expects key=value") {
+		t.Fatalf("expected --set error, got %q", stderr.String())
+	}
+}
+
+func TestRunModelFlagOverridesConfig(t *testing.T) {
+	dir := t.TempDir()
+	var gotModel string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		gotModel = req.Model
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"ok"}`)
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	}))
+	defer srv.Close()
+	config.Set(dir, "endpoint", "http://unused.invalid")
+	config.Set(dir, "api-key", "test")
+	config.Set(dir, "model", "file-model")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"-log", "--endpoint", srv.URL, "--model", "flag-model", "--set", "api-key=sk-supersecret", "hi"}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	if gotModel != "flag-model" {
+		t.Fatalf("model = %q, want flag-model", gotModel)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(dir, ".rai", "log"))
+	data, _ := os.ReadFile(filepath.Join(dir, ".rai", "log", entries[0].Name()))
+	if !strings.Contains(string(data), "model: flag-model") {
+		t.Fatalf("expected override in log header, got %q", data)
+	}
+	if strings.Contains(string(data), "sk-supersecret") {
+		t.Fatalf("secret override leaked into log header")
+	}
+}
+
+func TestRunRejectsInvalidTemperature(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"--temperature", "hot", "hi"}, &stdout, &stderr, t.TempDir())
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "temperature must be a number") {
+		t.Fatalf("expected validation error, got %q", stderr.String())
+	}
+}
+
+// --- Config get/list/unset tests ---
+
+func TestRunConfigGetListUnset(t *testing.T) {
+	dir := t.TempDir()
+	config.Set(dir, "model", "gpt-4")
+	config.Set(dir, "api-key", "sk-1234567890")
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"config", "get", "model"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("get exit code = %d (stderr %q)", code, stderr.String())
+	}
+	if strings.TrimSpace(stdout.String()) != "gpt-4" {
+		t.Fatalf("get model = %q", stdout.String())
+	}
+
+	stdout.Reset()
+	Run([]string{"config", "get", "api-key"}, &stdout, &stderr, dir)
+	if strings.TrimSpace(stdout.String()) != "sk-1****" {
+		t.Fatalf("expected masked api-key, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	Run([]string{"config", "list"}, &stdout, &stderr, dir)
+	if !strings.Contains(stdout.String(), "api-key = sk-1****") || !strings.Contains(stdout.String(), "model = gpt-4") {
+		t.Fatalf("unexpected list output %q", stdout.String())
+	}
+
+	stdout.Reset()
+	Run([]string{"config", "list", "--show-secrets"}, &stdout, &stderr, dir)
+	if !strings.Contains(stdout.String(), "api-key = sk-1234567890") {
+		t.Fatalf("expected unmasked secret, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"config", "unset", "model"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("unset exit code = %d", code)
+	}
+	stderr.Reset()
+	if code := Run([]string{"config", "get", "model"}, &stdout, &stderr, dir); code != 1 {
+		t.Fatalf("get after unset exit code = %d, want 1", code)
+	}
+	if code := Run([]string{"config", "unset", "model"}, &stdout, &stderr, dir); code != 1 {
+		t.Fatalf("second unset exit code = %d, want 1", code)
+	}
+}
+
+func TestRunConfigListResolved(t *testing.T) {
+	dir := t.TempDir()
+	config.Set(dir, "endpoint", "http://file")
+	config.Set(dir, "model", "file-model")
+	t.Setenv("RAI_COPILOT_TOKEN", "gho_abcdefghijk")
+	agentPath := filepath.Join(dir, "agent.md")
+	os.WriteFile(agentPath, []byte("---\nmodel: agent-model\n---\nHi.\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"config", "list", "--resolved", "--agent", agentPath, "--temperature", "0.5"}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"endpoint = http://file  (file)",
+		"model = agent-model  (agent)",
+		"temperature = 0.5  (cli)",
+		"copilot_token = gho_****  (env)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got %q", want, out)
+		}
+	}
+}
+
+func TestRunConfigSetExplicit(t *testing.T) {
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"config", "set", "model", "m1"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d", code)
+	}
+	values, _ := config.Load(dir)
+	if values["model"] != "m1" {
+		t.Fatalf("model = %q", values["model"])
+	}
+}
+
+func TestParseArgsOutput(t *testing.T) {
+	if p := ParseArgs([]string{"--output", "json", "hi"}); !p.JSON || p.Prompt != "hi" {
+		t.Fatalf("unexpected parse: %+v", p)
+	}
+	if p := ParseArgs([]string{"--output=text", "hi"}); p.JSON {
+		t.Fatalf("expected text output: %+v", p)
+	}
+	if p := ParseArgs([]string{"--output", "yaml", "hi"}); !strings.Contains(p.ArgError, "--output expects text or json") {
+		t.Fatalf("ArgError = %q", p.ArgError)
+	}
+}
+
+func TestRunJSONOutputSilent(t *testing.T) {
+	dir := t.TempDir()
+	writeMockProviderConfig(t, dir, func(int) string { return "the answer" })
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"--output", "json", "-silent", "question"}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected only the final object, got %q", stdout.String())
+	}
+	var ev output.JSONEvent
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if ev.Kind != output.EventFinal || ev.Text != "the answer" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestRunSchemaMismatchExitCode(t *testing.T) {
+	dir := t.TempDir()
+	writeMockProviderConfig(t, dir, func(int) string { return "plain text" })
+	schemaPath := filepath.Join(dir, "out.json")
+	os.WriteFile(schemaPath, []byte(`{"type":"object"}`), 0o644)
+	config.Set(dir, "schema-retries", "0")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"-silent", "--schema", schemaPath, "extract"}, &stdout, &stderr, dir)
+	if code != exitSchemaMismatch {
+		t.Fatalf("exit code = %d, want %d (stderr %q)", code, exitSchemaMismatch, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "does not match schema") {
+		t.Fatalf("expected schema error, got %q", stderr.String())
+	}
+}
+
+func TestRunAgentResponseSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeMockProviderConfig(t, dir, func(int) string { return `{"title":"x"}` })
+	os.MkdirAll(filepath.Join(dir, "agents", "schemas"), 0o755)
+	os.WriteFile(filepath.Join(dir, "agents", "schemas", "out.json"), []byte(`{"type":"object","required":["title"]}`), 0o644)
+	agentPath := filepath.Join(dir, "agents", "extract.md")
+	os.WriteFile(agentPath, []byte("---\nresponse-schema: schemas/out.json\n---\nExtract.\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"-silent", "--agent", agentPath, "go"}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	if strings.TrimSpace(stdout.String()) != `{"title":"x"}` {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+}
+
+func TestRunSchemaLoadError(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"--schema", "missing.json", "hi"}, &stdout, &stderr, t.TempDir())
+	if code != 1 || !strings.Contains(stderr.String(), "schema error") {
+		t.Fatalf("code = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestParseArgsVars(t *testing.T) {
+	p := ParseArgs([]string{"--var", "lang=Go", "--var=title=a=b", "hi"})
+	if p.ArgError != "" || p.Vars["lang"] != "Go" || p.Vars["title"] != "a=b" {
+		t.Fatalf("unexpected parse: %+v", p)
+	}
+	for _, bad := range []string{"novalue", "bad-name=x", "=x"} {
+		if p := ParseArgs([]string{"--var", bad, "hi"}); !strings.Contains(p.ArgError, "--var") {
+			t.Errorf("--var %s: ArgError = %q", bad, p.ArgError)
+		}
+	}
+}
+
+func TestRunRendersAgentTemplate(t *testing.T) {
+	dir := t.TempDir()
+	agentPath := filepath.Join(dir, "review.md")
+	os.WriteFile(agentPath, []byte("---\nvars:\n  lang: Go\n  focus: style\n---\nReview {{.lang}} for {{.focus}} with {{.model}}.\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"-log", "--agent", agentPath, "--model", "m1", "--var", "focus=bugs", "go"}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, ".rai", "log"))
+	if len(entries) == 0 {
+		t.Fatal("expected log file")
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".rai", "log", entries[0].Name()))
+	log := string(data)
+	if !strings.Contains(log, "Review Go for bugs with m1.") {
+		t.Fatalf("expected rendered system prompt in log, got %q", log)
+	}
+	if !strings.Contains(log, "var.focus") {
+		t.Fatalf("expected --var in log header, got %q", log)
+	}
+}
+
+func TestRunExpandsAgentIncludes(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "agents", "docs"), 0o755)
+	os.WriteFile(filepath.Join(dir, "agents", "docs", "style.md"), []byte("Prefer {{short}} names.\n"), 0o644)
+	agentPath := filepath.Join(dir, "agents", "review.md")
+	os.WriteFile(agentPath, []byte("Review {{.lang}} code.\n@file:docs/style.md\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"-log", "--agent", agentPath, "--var", "lang=Go", "go"}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, ".rai", "log"))
+	if len(entries) == 0 {
+		t.Fatal("expected log file")
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".rai", "log", entries[0].Name()))
+	log := string(data)
+	if !strings.Contains(log, "Review Go code.\nPrefer {{short}} names.") {
+		t.Fatalf("expected expanded system prompt in log, got %q", log)
+	}
+	if !strings.Contains(log, "includes: "+filepath.Join(dir, "agents", "docs", "style.md")) {
+		t.Fatalf("expected includes in log header, got %q", log)
+	}
+}
+
+func TestRunIncludesFromExtendedParent(t *testing.T) {
+	// The parent lives in a sibling of the workspace, outside both the
+	// workspace and the child agent's directory, and includes its neighbour.
+	root := t.TempDir()
+	dir := filepath.Join(root, "project")
+	os.MkdirAll(dir, 0o755)
+	os.MkdirAll(filepath.Join(root, "shared"), 0o755)
+	os.WriteFile(filepath.Join(root, "shared", "tone.md"), []byte("Be terse.\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "shared", "base.md"), []byte("Base.\n@file:tone.md\n"), 0o644)
+	agentPath := filepath.Join(dir, "agent.md")
+	os.WriteFile(agentPath, []byte("---\nextends: ../shared/base.md\n---\nChild.\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"-log", "--agent", agentPath, "go"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, ".rai", "log"))
+	if len(entries) == 0 {
+		t.Fatal("expected log file")
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".rai", "log", entries[0].Name()))
+	if !strings.Contains(string(data), "Base.\nBe terse.\n\nChild.") {
+		t.Fatalf("expected the parent's include expanded, got %q", data)
+	}
+}
+
+func TestRunIncludeOutsideWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	agentPath := filepath.Join(dir, "agent.md")
+	os.WriteFile(agentPath, []byte("@file:../../etc/passwd\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"--agent", agentPath, "go"}, &stdout, &stderr, dir)
+	if code != 1 || !strings.Contains(stderr.String(), "outside the workspace") {
+		t.Fatalf("code = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestRunMissingTemplateVariable(t *testing.T) {
+	dir := t.TempDir()
+	agentPath := filepath.Join(dir, "review.md")
+	os.WriteFile(agentPath, []byte("---\nvars:\n  ticket:\n---\nTicket {{.ticket}}\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"--agent", agentPath, "go"}, &stdout, &stderr, dir)
+	if code != 1 || !strings.Contains(stderr.String(), `missing required variable "ticket"`) {
+		t.Fatalf("code = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestRunRendersPromptFile(t *testing.T) {
+	dir := t.TempDir()
+	promptPath := filepath.Join(dir, "prompt.txt")
+	os.WriteFile(promptPath, []byte("Summarize {{.topic}}"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"-silent", "--prompt-file", promptPath, "--var", "topic=logs"}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Summarize logs") {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+}
+
+func TestRunKeepsTemplateSyntaxWithoutVars(t *testing.T) {
+	dir := t.TempDir()
+	promptPath := filepath.Join(dir, "deploy.txt")
+	os.WriteFile(promptPath, []byte("Tag the image with ${{ github.sha }}"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.md"), []byte("Notes."), 0o644)
+	agentPath := filepath.Join(dir, "helm.md")
+	os.WriteFile(agentPath, []byte("Charts use {{ .Values.image }}.\n@file:notes.md\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"-log", "--agent", agentPath, "--prompt-file", promptPath}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Tag the image with ${{ github.sha }}") {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, ".rai", "log"))
+	if len(entries) == 0 {
+		t.Fatal("expected log file")
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".rai", "log", entries[0].Name()))
+	if !strings.Contains(string(data), "Charts use {{ .Values.image }}.\nNotes.") {
+		t.Fatalf("expected verbatim body with includes expanded, got %q", data)
+	}
+
+	// template: true opts in without --var.
+	os.WriteFile(agentPath, []byte("---\ntemplate: true\n---\nOn {{.os}}.\n"), 0o644)
+	stdout.Reset()
+	code = Run([]string{"--agent", agentPath, "--prompt-file", promptPath}, &stdout, &stderr, dir)
+	if code != 1 || !strings.Contains(stderr.String(), "prompt error") {
+		t.Fatalf("code = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestParseFileSpec(t *testing.T) {
+	cases := []struct {
+		spec       string
+		pattern    string
+		start, end int
+		wantErr    bool
+	}{
+		{spec: "main.go", pattern: "main.go"},
+		{spec: "main.go:10-20", pattern: "main.go", start: 10, end: 20},
+		{spec: "main.go:10-", pattern: "main.go", start: 10},
+		{spec: "main.go:-5", pattern: "main.go", end: 5},
+		{spec: "main.go:7", pattern: "main.go", start: 7, end: 7},
+		{spec: "src/**/*.go:1-3", pattern: "src/**/*.go", start: 1, end: 3},
+		{spec: "main.go:20-10", wantErr: true},
+		{spec: "main.go:0-3", wantErr: true},
+		{spec: "main.go:-", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := parseFileSpec(tc.spec)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error", tc.spec)
+			}
+			continue
+		}
+		if err != nil || got.Pattern != tc.pattern || got.Start != tc.start || got.End != tc.end {
+			t.Errorf("%s: got %+v, %v", tc.spec, got, err)
+		}
+	}
+}
+
+func TestLoadAttachments(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src", "sub"), 0o755)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0o755)
+	os.WriteFile(filepath.Join(dir, "src", "a.go"), []byte("package a\n\nfunc A() {}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "src", "sub", "b.go"), []byte("package sub\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "src", "sub", "c.go"), []byte("pack\x00age"), 0o644)
+	os.WriteFile(filepath.Join(dir, ".git", "x.go"), []byte("hidden"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("one\ntwo\nthree\n"), 0o644)
+
+	atts, warnings, err := loadAttachments([]string{"**/*.go", "notes.txt:2-3", "src/a.go"}, dir, map[string]string{})
+	if err != nil {
+		t.Fatalf("loadAttachments: %v", err)
+	}
+	if got := attachmentLabels(atts); got != "src/a.go, src/sub/b.go, notes.txt:2-3" {
+		t.Fatalf("labels = %q", got)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "c.go") {
+		t.Fatalf("warnings = %v", warnings)
+	}
+	if atts[2].Content != "two\nthree" || atts[2].Language != "text" || atts[0].Language != "go" {
+		t.Fatalf("attachments = %+v", atts)
+	}
+
+	if _, _, err := loadAttachments([]string{"src/sub/c.go"}, dir, map[string]string{}); err == nil || !strings.Contains(err.Error(), "not valid UTF-8") {
+		t.Fatalf("expected binary error, got %v", err)
+	}
+	if _, _, err := loadAttachments([]string{"*.rs"}, dir, map[string]string{}); err == nil || !strings.Contains(err.Error(), "matches no files") {
+		t.Fatalf("expected no-match error, got %v", err)
+	}
+	if _, _, err := loadAttachments([]string{"src/**/*.go"}, dir, map[string]string{"attach-max-bytes": "10"}); err == nil || !strings.Contains(err.Error(), "exceed 10 bytes") {
+		t.Fatalf("expected size error, got %v", err)
+	}
+	if _, _, err := loadAttachments([]string{"notes.txt:9-10"}, dir, map[string]string{}); err == nil {
+		t.Fatal("expected error for range past end of file")
+	}
+
+	// A line range of a file larger than the limit only counts the lines
+	// it selects.
+	os.WriteFile(filepath.Join(dir, "big.txt"), []byte(strings.Repeat("0123456789\n", 1000)+strings.Repeat("x", 8192)+"\nlast\n"), 0o644)
+	small := map[string]string{"attach-max-bytes": "32"}
+	if atts, _, err := loadAttachments([]string{"big.txt:500-501"}, dir, small); err != nil || atts[0].Content != "0123456789\n0123456789" {
+		t.Fatalf("range of big file = %+v, %v", atts, err)
+	}
+	if atts, _, err := loadAttachments([]string{"big.txt:1002"}, dir, small); err != nil || atts[0].Content != "last" {
+		t.Fatalf("line after long line = %+v, %v", atts, err)
+	}
+	for _, spec := range []string{"big.txt", "big.txt:1-10", "big.txt:1001"} {
+		if _, _, err := loadAttachments([]string{spec}, dir, small); err == nil || !strings.Contains(err.Error(), "exceed 32 bytes") {
+			t.Errorf("%s: expected size error, got %v", spec, err)
+		}
+	}
+}
+
+func TestRunAttachesFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"-silent", "-log", "--file", "main.go", "explain"}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	want := "explain\n\n--- file: main.go (go) ---\npackage main\n--- end file ---"
+	if !strings.Contains(stdout.String(), want) {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, ".rai", "log"))
+	if len(entries) == 0 {
+		t.Fatal("expected log file")
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".rai", "log", entries[0].Name()))
+	if !strings.Contains(string(data), "files") || !strings.Contains(string(data), "main.go") {
+		t.Fatalf("expected attached files in log header, got %q", data)
+	}
+}
+
+// pngHeader is enough of a PNG file for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestLoadImages(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "shot.png"), pngHeader, 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.png"), []byte("not an image"), 0o644)
+
+	parts, err := loadImages([]string{"shot.png"}, dir)
+	if err != nil {
+		t.Fatalf("loadImages: %v", err)
+	}
+	if len(parts) != 1 || parts[0].Type != provider.PartImage || parts[0].MIMEType != "image/png" || parts[0].Data == "" {
+		t.Fatalf("parts = %+v", parts)
+	}
+	if _, err := loadImages([]string{"notes.png"}, dir); err == nil || !strings.Contains(err.Error(), "not a PNG") {
+		t.Fatalf("expected format error, got %v", err)
+	}
+	if _, err := loadImages([]string{"missing.png"}, dir); err == nil {
+		t.Fatal("expected error for missing image")
+	}
+}
+
+func TestRunImageRequiresVisionModel(t *testing.T) {
+	dir := t.TempDir()
+	writeMockProviderConfig(t, dir, func(int) string { return "a cat" })
+	os.WriteFile(filepath.Join(dir, "shot.png"), pngHeader, 0o644)
+
+	// gpt-3.5 is known to lack vision; test-model is unknown and let
+	// through without listing models.
+	for _, tc := range []struct {
+		model string
+		code  int
+	}{{"gpt-3.5-turbo", 1}, {"gpt-4o", 0}, {"test-model", 0}} {
+		var stdout, stderr bytes.Buffer
+		code := Run([]string{"-silent", "--model", tc.model, "--image", "shot.png", "what is this?"}, &stdout, &stderr, dir)
+		if code != tc.code {
+			t.Fatalf("%s: code = %d, stdout = %q, stderr = %q", tc.model, code, stdout.String(), stderr.String())
+		}
+		if code == 1 && !strings.Contains(stderr.String(), "does not accept image input") {
+			t.Errorf("%s: stderr = %q", tc.model, stderr.String())
+		}
+		if code == 0 && (!strings.Contains(stdout.String(), "a cat") || stderr.Len() > 0) {
+			t.Errorf("%s: stdout = %q, stderr = %q", tc.model, stdout.String(), stderr.String())
+		}
+	}
+}
+
+func TestRunAgentsListShowAndByName(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".rai", "agents"), 0o755)
+	os.WriteFile(filepath.Join(dir, ".rai", "agents", "code-reviewer.md"),
+		[]byte("---\nname: reviewer\ndescription: Reviews diffs\napi-key: sk-secret-value\n---\nYou review code.\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"agents", "list"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("list exit code = %d (stderr %q)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "reviewer\n  Reviews diffs") {
+		t.Fatalf("list = %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"agents", "show", "reviewer"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("show exit code = %d (stderr %q)", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "name: reviewer") || !strings.Contains(out, "You review code.") || strings.Contains(out, "sk-secret-value") {
+		t.Fatalf("show = %q", out)
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"-log", "--agent", "reviewer", "hi"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("prompt exit code = %d (stderr %q)", code, stderr.String())
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, ".rai", "log"))
+	if len(entries) == 0 {
+		t.Fatal("expected log file")
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".rai", "log", entries[0].Name()))
+	if !strings.Contains(string(data), "You review code.") {
+		t.Fatalf("expected named agent in log, got %q", data)
+	}
+
+	stderr.Reset()
+	if code := Run([]string{"--agent", "nobody", "hi"}, &stdout, &stderr, dir); code != 1 || !strings.Contains(stderr.String(), `agent "nobody" not found`) {
+		t.Fatalf("code = %d, stderr = %q", code, stderr.String())
+	}
+	if code := Run([]string{"agents", "show", "nobody"}, &stdout, &stderr, dir); code != 1 {
+		t.Fatalf("show missing exit code = %d", code)
+	}
+}
+
+func TestRunCompletionScripts(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var stdout, stderr bytes.Buffer
+		if code := Run([]string{"completion", shell}, &stdout, &stderr, t.TempDir()); code != 0 {
+			t.Fatalf("%s: exit code = %d", shell, code)
+		}
+		if !strings.Contains(stdout.String(), "rai __complete") {
+			t.Errorf("%s script does not call back into rai: %q", shell, stdout.String())
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"completion", "tcsh"}, &stdout, &stderr, t.TempDir()); code != 2 {
+		t.Fatalf("unsupported shell exit code = %d", code)
+	}
+}
+
+func TestBashCompletionJoinsEqualsFlags(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	// The stub rai records the words it receives and offers json for the
+	// last one, the way rai __complete would for --output=.
+	script := completionScripts["bash"] + `
+rai() { shift; printf '%s|' "$@" >"$RAI_ARGS"; printf '%s\n' "${@: -1}son"; }
+compopt() { :; }
+COMP_LINE='rai --output=j' COMP_POINT=14 COMP_WORDS=(rai --output = j) COMP_CWORD=3
+_rai
+printf '%s\n' "${COMPREPLY[@]}"
+`
+	argsFile := filepath.Join(t.TempDir(), "args")
+	cmd := exec.Command(bash, "-c", script)
+	cmd.Env = append(os.Environ(), "RAI_ARGS="+argsFile)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("bash: %v", err)
+	}
+	if got, _ := os.ReadFile(argsFile); string(got) != "--output=j|" {
+		t.Errorf("rai __complete got words %q, want --output=j", got)
+	}
+	if got := strings.TrimSpace(string(out)); got != "json" {
+		t.Errorf("COMPREPLY = %q, want json", got)
+	}
+}
+
+func TestCompleteWords(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "agents"), 0o755)
+	os.WriteFile(filepath.Join(dir, "agents", "reviewer.md"), []byte("Review."), 0o644)
+	os.WriteFile(filepath.Join(dir, "agents", "notes.txt"), []byte("x"), 0o644)
+	os.MkdirAll(filepath.Join(dir, ".rai", "agents"), 0o755)
+	os.WriteFile(filepath.Join(dir, ".rai", "agents", "code-reviewer.md"), []byte("---\nname: code-reviewer\n---\nReview."), 0o644)
+
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"co"}, "completion,config,copilot-login"},
+		{[]string{"--sch"}, "--schema"},
+		{[]string{"config", "--re"}, "--resolved,--resume"},
+		{[]string{"config", "get", "max"}, "max-output-tokens,max-tokens"},
+		{[]string{"config", "provider", ""}, "github-copilot,github-copilot-enterprise"},
+		{[]string{"--agent", "agents/"}, "agents/reviewer.md"},
+		{[]string{"--agent=ag"}, "--agent=agents/"},
+		{[]string{"--agent", "code"}, "code-reviewer"},
+		{[]string{"agents", "show", ""}, "code-reviewer"},
+		{[]string{"agents", ""}, "lint,list,show"},
+		{[]string{"--set", "top"}, "top-p="},
+		{[]string{"--output", ""}, "json,text"},
+		{[]string{"-silent", "--model", "x", "ses"}, "sessions"},
+		{[]string{"completion", "z"}, "zsh"},
+		{[]string{"hello", "world"}, ""},
+	}
+	for _, tt := range tests {
+		got := strings.Join(completeWords(tt.words, dir), ",")
+		if got != tt.want {
+			t.Errorf("completeWords(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteSkillAndSessionNames(t *testing.T) {
+	dir := t.TempDir()
+	skillDir := filepath.Join(dir, ".rai", "skills", "read-file")
+	os.MkdirAll(skillDir, 0o755)
+	os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: read-file\ndescription: Reads a file.\n---\nBody\n"), 0o644)
+	if got := skillNames(dir); len(got) != 1 || got[0] != "read-file" {
+		t.Fatalf("skillNames = %v", got)
+	}
+	for words, want := range map[string]string{
+		"skills |":      "list,run,show,validate",
+		"skills show |": "read-file",
+		"skills run |r": "read-file",
+	} {
+		line, cur, _ := strings.Cut(words, "|")
+		if got := strings.Join(completeWords(append(strings.Fields(line), cur), dir), ","); got != want {
+			t.Errorf("completeWords(%q) = %q, want %q", words, got, want)
+		}
+	}
+
+	rec := session.NewRecord(time.Now())
+	if err := session.SaveRecord(dir, rec); err != nil {
+		t.Fatalf("SaveRecord: %v", err)
+	}
+	var stdout bytes.Buffer
+	Run([]string{"__complete", "sessions", "show", ""}, &stdout, io.Discard, dir)
+	if strings.TrimSpace(stdout.String()) != rec.ID {
+		t.Fatalf("expected session ID %s, got %q", rec.ID, stdout.String())
+	}
+}
+
+func TestRunModelsCachesAndRefreshes(t *testing.T) {
+	dir := t.TempDir()
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"data":[{"id":"gpt-4o"},{"id":"gpt-4.1"}]}`)
+	}))
+	defer srv.Close()
+	config.Set(dir, "endpoint", srv.URL)
+	config.Set(dir, "api-key", "test")
+	config.Set(dir, "model", "gpt-4o")
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"models"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d (stderr %q)", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "* gpt-4o") || !strings.Contains(stdout.String(), "  gpt-4.1") {
+		t.Fatalf("unexpected table: %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"models", "--json"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d", code)
+	}
+	if requests != 1 {
+		t.Fatalf("expected cached second call, got %d requests", requests)
+	}
+	var models []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &models); err != nil || len(models) != 2 {
+		t.Fatalf("invalid JSON output %q: %v", stdout.String(), err)
+	}
+
+	if code := Run([]string{"models", "--refresh"}, &stdout, &stderr, dir); code != 0 {
+		t.Fatalf("exit code = %d", code)
+	}
+	if requests != 2 {
+		t.Fatalf("expected --refresh to refetch, got %d requests", requests)
+	}
+}
+
+func TestRunModelsWithoutProvider(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"models"}, &stdout, &stderr, t.TempDir()); code != 1 {
+		t.Fatalf("exit code = %d", code)
+	}
+	if !strings.Contains(stderr.String(), "provider error") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestRunDoctorPasses(t *testing.T) {
+	dir := t.TempDir()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"output":[{"type":"message","content":[{"type":"output_text","text":"OK"}]}]}`)
+	}))
+	defer srv.Close()
+	config.Set(dir, "endpoint", srv.URL)
+	config.Set(dir, "api-key", "sk-doctor-test")
+	config.Set(dir, "model", "test-model")
+	os.MkdirAll(filepath.Join(dir, "agents"), 0o755)
+	os.WriteFile(filepath.Join(dir, "agents", "odd.md"), []byte("---\nflavor: x\n---\nBody\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"doctor"}, &stdout, &stderr, dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d, output:\n%s", code, stdout.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{
+		"[PASS] config: 3 value(s) in .rai/config",
+		"[PASS] provider: openai, model test-model",
+		"[PASS] credentials: api-key sk-d**** (file)",
+		"[PASS] request: ",
+		"[WARN] agent agents/odd.md: unknown agent key: flavor",
+		"[PASS] skills: 0 skill(s)",
+		"all checks passed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in report:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "sk-doctor-test") {
+		t.Errorf("secret leaked in report")
+	}
+}
+
+func TestRunDoctorReportsProviderError(t *testing.T) {
+	dir := t.TempDir()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	config.Set(dir, "endpoint", srv.URL)
+	config.Set(dir, "api-key", "bad")
+	config.Set(dir, "model", "m")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"doctor", "--json"}, &stdout, &stderr, dir)
+	if code != 1 {
+		t.Fatalf("exit code = %d", code)
+	}
+	var report struct {
+		OK     bool `json:"ok"`
+		Checks []struct {
+			Name     string `json:"name"`
+			Status   string `json:"status"`
+			Detail   string `json:"detail"`
+			Guidance string `json:"guidance"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if report.OK {
+		t.Fatalf("expected ok=false")
+	}
+	found := false
+	for _, c := range report.Checks {
+		if c.Name == "request" {
+			found = true
+			if c.Status != "fail" || !strings.Contains(c.Detail, "authentication failed") || !strings.Contains(c.Guidance, "rai config api-key") {
+				t.Errorf("unexpected request check: %+v", c)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("request check missing")
+	}
+}
+
+func TestRunDoctorWithoutProvider(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"doctor"}, &stdout, &stderr, t.TempDir())
+	if code != 1 {
+		t.Fatalf("exit code = %d", code)
+	}
+	out := stdout.String()
+	for _, want := range []string{"[FAIL] provider: no provider", "rai config endpoint", "[FAIL] credentials", "[SKIP] request"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in report:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunDoctorStoredCopilotToken(t *testing.T) {
+	dir := t.TempDir()
+	config.Set(dir, "provider", "github-copilot")
+	provider.SaveCopilotToken(dir, "gho_storedtoken")
+
+	var stdout, stderr bytes.Buffer
+	Run([]string{"doctor", "--no-request"}, &stdout, &stderr, dir)
+	out := stdout.String()
+	if !strings.Contains(out, "[PASS] credentials: stored Copilot token gho_****") || !strings.Contains(out, "[SKIP] request: --no-request") {
+		t.Fatalf("unexpected report:\n%s", out)
+	}
+}
+
+func TestDelegateKeepsAgentModel(t *testing.T) {
+	dir := t.TempDir()
+	var models []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		models = append(models, body.Model)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"done"}`)
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	}))
+	defer srv.Close()
+	config.Set(dir, "api-key", "test")
+	agentsDir := agent.AgentsDir(dir)
+	os.MkdirAll(agentsDir, 0o755)
+	os.WriteFile(filepath.Join(agentsDir, "specialist.md"), []byte("---\ndescription: Specialist.\nmodel: specialist-model\n---\nYou specialise.\n"), 0o644)
+
+	sink, _ := output.NewSink(output.Options{Console: io.Discard})
+	defer sink.Close()
+	p := Parsed{Overrides: map[string]string{"model": "lead-model", "temperature": "0.1", "endpoint": srv.URL}}
+	d, err := newDelegate(p, map[string]string{}, dir, sink)
+	if err != nil || d == nil {
+		t.Fatalf("newDelegate = %v, %v", d, err)
+	}
+	if _, err := d.Run(context.Background(), "specialist", "task"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(models) != 1 || models[0] != "specialist-model" {
+		t.Errorf("models = %q, want the specialist's model", models)
+	}
+}
+
+func TestDelegateRunsNamedAgent(t *testing.T) {
+	dir := t.TempDir()
+	writeMockProviderConfig(t, dir, func(n int) string { return "reviewed" })
+	agentsDir := agent.AgentsDir(dir)
+	os.MkdirAll(agentsDir, 0o755)
+	os.WriteFile(filepath.Join(agentsDir, "reviewer.md"), []byte("---\ndescription: Reviews code.\n---\nYou review code.\n"), 0o644)
+
+	var console bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &console})
+	defer sink.Close()
+
+	d, err := newDelegate(Parsed{}, map[string]string{}, dir, sink)
+	if err != nil || d == nil {
+		t.Fatalf("newDelegate = %v, %v", d, err)
+	}
+	if len(d.Agents) != 1 || d.Agents[0].Name != "reviewer" || d.Agents[0].Description != "Reviews code." {
+		t.Fatalf("agents = %+v", d.Agents)
+	}
+	answer, err := d.Run(context.Background(), "reviewer", "check main.go")
+	if err != nil || answer != "reviewed" {
+		t.Fatalf("Run = %q, %v", answer, err)
+	}
+	if !strings.Contains(console.String(), "[reviewer] [AI] reviewed") {
+		t.Errorf("nested events not labelled: %q", console.String())
+	}
+
+	if d, err := newDelegate(Parsed{}, map[string]string{"delegate-max-depth": "0"}, dir, sink); err != nil || d != nil {
+		t.Errorf("depth 0 should disable delegation, got %v, %v", d, err)
+	}
+	if _, err := newDelegate(Parsed{}, map[string]string{"delegate-max-iterations": "0"}, dir, sink); err == nil {
+		t.Error("expected an error for delegate-max-iterations 0")
+	}
+}
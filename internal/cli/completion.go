@@ -0,0 +1,336 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"run-ai/internal/config"
+	"run-ai/internal/session"
+	"run-ai/internal/skills"
+)
+
+// completeCommand is the hidden command the completion scripts call back into.
+// It prints one candidate per line for the last word on the command line.
+const completeCommand = "__complete"
+
+// subcommands lists the commands offered as the first positional word.
+var subcommands = []string{"agents", "chat", "completion", "config", "copilot-login", "doctor", "models", "sessions", "skills"}
+
+// globalFlags lists the flags accepted in prompt and chat mode.
+var globalFlags = []string{
+	"--agent", "--continue", "--endpoint", "--file", "--help", "--image", "--max-tokens", "--model",
+	"--output", "--prompt-file", "--provider", "--resume", "--schema", "--set",
+	"--strict-agent", "--temperature", "--var", "-log", "-silent",
+}
+
+// valueFlags are the flags that consume the following word.
+var valueFlags = map[string]bool{
+	"--agent": true, "--endpoint": true, "--file": true, "--image": true, "--max-tokens": true, "--model": true,
+	"--output": true, "--prompt-file": true, "--provider": true, "--resume": true,
+	"--schema": true, "--set": true, "--temperature": true, "--var": true,
+}
+
+// commandFlags lists the flags specific to a subcommand.
+var commandFlags = map[string][]string{
+	"config": {"--resolved", "--show-secrets"},
+	"doctor": {"--json", "--no-request"},
+	"models": {"--json", "--refresh"},
+	"skills": {"--json", "--json-args"},
+}
+
+// skillsSubcommands maps each `rai skills` subcommand to whether its first
+// argument is a skill name.
+var skillsSubcommands = map[string]bool{
+	"list":     false,
+	"run":      true,
+	"show":     true,
+	"validate": false,
+}
+
+// runCompletion implements `rai completion bash|zsh|fish`.
+func runCompletion(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		writeUsage(stderr)
+		return 2
+	}
+	script, ok := completionScripts[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "completion error: unsupported shell %q (use bash, zsh or fish)\n", args[0])
+		return 2
+	}
+	fmt.Fprint(stdout, script)
+	return 0
+}
+
+// runComplete implements the hidden `rai __complete <words...>` command.
+// words are the command-line words after "rai"; the last one is the word
+// being completed and may be empty.
+func runComplete(words []string, stdout io.Writer, baseDir string) int {
+	for _, c := range completeWords(words, baseDir) {
+		fmt.Fprintln(stdout, c)
+	}
+	return 0
+}
+
+// completeWords returns the sorted candidates for the last word.
+func completeWords(words []string, baseDir string) []string {
+	cur := ""
+	if len(words) > 0 {
+		cur = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	if n := len(words); n > 0 && valueFlags[words[n-1]] {
+		return filterPrefix(completeFlagValue(words[n-1], cur, baseDir), cur)
+	}
+	if name, value, ok := strings.Cut(cur, "="); ok && valueFlags[name] {
+		var out []string
+		for _, c := range filterPrefix(completeFlagValue(name, value, baseDir), value) {
+			out = append(out, name+"="+c)
+		}
+		return out
+	}
+
+	positional := positionalWords(words)
+	if strings.HasPrefix(cur, "-") {
+		flags := globalFlags
+		if len(positional) > 0 {
+			flags = append(append([]string{}, commandFlags[positional[0]]...), flags...)
+		}
+		return filterPrefix(flags, cur)
+	}
+	if len(positional) == 0 {
+		return filterPrefix(subcommands, cur)
+	}
+
+	var candidates []string
+	args := positional[1:]
+	switch positional[0] {
+	case "completion":
+		if len(args) == 0 {
+			candidates = []string{"bash", "fish", "zsh"}
+		}
+	case "config":
+		switch {
+		case len(args) == 0:
+			candidates = append([]string{"get", "list", "set", "unset"}, config.KnownKeys()...)
+		case len(args) == 1 && (args[0] == "get" || args[0] == "set" || args[0] == "unset"):
+			candidates = config.KnownKeys()
+		case len(args) == 1 && args[0] == "provider", len(args) == 2 && args[0] == "set" && args[1] == "provider":
+			candidates = providerNames
+		}
+	case "agents":
+		switch {
+		case len(args) == 0:
+			candidates = []string{"lint", "list", "show"}
+		case len(args) == 1 && args[0] == "show":
+			candidates = agentNames(baseDir)
+		case len(args) >= 1 && args[0] == "lint":
+			candidates = append(agentNames(baseDir), completeFiles(cur, baseDir, ".md")...)
+		}
+	case "sessions":
+		switch {
+		case len(args) == 0:
+			candidates = []string{"delete", "list", "show"}
+		case len(args) == 1 && (args[0] == "show" || args[0] == "delete"):
+			candidates = sessionIDs(baseDir)
+		}
+	case "skills":
+		switch {
+		case len(args) == 0:
+			for name := range skillsSubcommands {
+				candidates = append(candidates, name)
+			}
+		case len(args) == 1 && skillsSubcommands[args[0]]:
+			candidates = skillNames(baseDir)
+		}
+	}
+	return filterPrefix(candidates, cur)
+}
+
+// providerNames are the values accepted by the provider key.
+var providerNames = []string{"github-copilot", "github-copilot-enterprise"}
+
+// completeFlagValue returns candidates for the value of flag.
+func completeFlagValue(flag, cur, baseDir string) []string {
+	switch flag {
+	case "--agent":
+		return append(agentNames(baseDir), completeFiles(cur, baseDir, ".md")...)
+	case "--prompt-file", "--file", "--image":
+		return completeFiles(cur, baseDir, "")
+	case "--schema":
+		return completeFiles(cur, baseDir, ".json")
+	case "--output":
+		return []string{"json", "text"}
+	case "--provider":
+		return providerNames
+	case "--resume":
+		return sessionIDs(baseDir)
+	case "--set":
+		var keys []string
+		for _, key := range config.KnownKeys() {
+			keys = append(keys, key+"=")
+		}
+		return keys
+	}
+	return nil
+}
+
+// positionalWords drops flags and their values from words.
+func positionalWords(words []string) []string {
+	var out []string
+	for i := 0; i < len(words); i++ {
+		switch {
+		case valueFlags[words[i]]:
+			i++
+		case strings.HasPrefix(words[i], "-"):
+		default:
+			out = append(out, words[i])
+		}
+	}
+	return out
+}
+
+// completeFiles lists the directory part of cur (relative to baseDir) and
+// returns paths starting with cur: directories with a trailing slash, and
+// files with the given extension (any file when ext is empty).  Dotfiles are
+// only offered once the user has typed the dot.
+func completeFiles(cur, baseDir, ext string) []string {
+	dir, prefix := filepath.Split(cur)
+	listDir := dir
+	if !filepath.IsAbs(listDir) {
+		listDir = filepath.Join(baseDir, dir)
+	}
+	entries, err := os.ReadDir(listDir)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(filepath.Join(listDir, name)); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		switch {
+		case isDir:
+			out = append(out, dir+name+"/")
+		case ext == "" || strings.EqualFold(filepath.Ext(name), ext):
+			out = append(out, dir+name)
+		}
+	}
+	return out
+}
+
+// skillNames returns the names of the skills discovered under baseDir.
+func skillNames(baseDir string) []string {
+	discovered, _, err := skills.Discover(baseDir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(discovered))
+	for _, s := range discovered {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// sessionIDs returns the IDs of the saved sessions under baseDir.
+func sessionIDs(baseDir string) []string {
+	records, err := session.ListRecords(baseDir)
+	if err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+// filterPrefix returns the sorted, de-duplicated candidates starting with prefix.
+func filterPrefix(candidates []string, prefix string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) && !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// completionScripts holds the script printed by `rai completion <shell>`.
+// Each one hands the words typed so far to `rai __complete`, so candidates
+// (including skill names, sessions and files) always match the binary.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for rai
+# Load with: source <(rai completion bash)
+_rai() {
+    local IFS=$'\n' line=${COMP_LINE:0:COMP_POINT} word cur
+    local -a words=()
+    local i
+    # Bash splits --flag=value at "=" (see COMP_WORDBREAKS); glue those
+    # pieces back together so rai sees the words as typed.
+    for ((i = 0; i <= COMP_CWORD; i++)); do
+        word=${COMP_WORDS[i]}
+        if [[ $line == [[:space:]]* ]]; then
+            line=${line#"${line%%[![:space:]]*}"}
+        elif [[ ${#words[@]} -gt 0 && ( $word == = || ${words[-1]} == *= ) ]]; then
+            words[-1]+=${line:0:${#word}}
+            line=${line:${#word}}
+            continue
+        fi
+        words+=("${line:0:${#word}}")
+        line=${line:${#word}}
+    done
+    cur=${words[-1]}
+    COMPREPLY=($(rai __complete "${words[@]:1}" 2>/dev/null))
+    # Readline only replaces the text after the last "=".
+    if [[ $cur == *=* && $COMP_WORDBREAKS == *=* ]]; then
+        COMPREPLY=("${COMPREPLY[@]#"${cur%=*}="}")
+    fi
+    if [[ ${#COMPREPLY[@]} -eq 1 && ( ${COMPREPLY[0]} == */ || ${COMPREPLY[0]} == *= ) ]]; then
+        compopt -o nospace
+    fi
+}
+complete -F _rai rai
+`,
+	"zsh": `#compdef rai
+# zsh completion for rai
+# Load with: source <(rai completion zsh)
+_rai() {
+    local -a candidates
+    local c
+    candidates=(${(f)"$(rai __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    for c in $candidates; do
+        if [[ $c == */ || $c == *= ]]; then
+            compadd -Q -S '' -- "$c"
+        else
+            compadd -Q -- "$c"
+        fi
+    done
+}
+compdef _rai rai
+`,
+	"fish": `# fish completion for rai
+# Load with: rai completion fish | source
+function __rai_complete
+    set -l tokens (commandline -opc)
+    set -l cur (commandline -ct)
+    rai __complete $tokens[2..-1] "$cur" 2>/dev/null
+end
+complete -c rai -f -a '(__rai_complete)'
+`,
+}
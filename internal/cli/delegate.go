@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"run-ai/internal/agent"
+	"run-ai/internal/config"
+	"run-ai/internal/output"
+	"run-ai/internal/provider"
+	"run-ai/internal/session"
+)
+
+// Delegation limits used when the delegate-max-depth and
+// delegate-max-iterations config keys are not set.
+const (
+	defaultDelegateMaxDepth      = 2
+	defaultDelegateMaxIterations = 5
+)
+
+// delegator runs delegated tasks with the named agents in .rai/agents/.  Each
+// delegated session gets its own delegator one level deeper, so the depth
+// limit bounds agents delegating to each other.
+type delegator struct {
+	p             Parsed
+	baseDir       string
+	sink          *output.Sink
+	agents        []agent.Agent
+	depth         int
+	maxDepth      int
+	maxIterations int
+}
+
+// newDelegate returns the delegate tool configuration for a top-level
+// session, or nil when delegation is disabled (delegate-max-depth 0) or no
+// named agents exist.
+func newDelegate(p Parsed, merged map[string]string, baseDir string, sink *output.Sink) (*session.Delegate, error) {
+	maxDepth, err := delegateLimit(merged, "delegate-max-depth", defaultDelegateMaxDepth, 0)
+	if err != nil {
+		return nil, err
+	}
+	maxIterations, err := delegateLimit(merged, "delegate-max-iterations", defaultDelegateMaxIterations, 1)
+	if err != nil {
+		return nil, err
+	}
+	agents, _, err := agent.Discover(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	d := &delegator{p: p, baseDir: baseDir, sink: sink, agents: agents, maxDepth: maxDepth, maxIterations: maxIterations}
+	return d.delegate(), nil
+}
+
+// delegateLimit parses a non-negative integer limit no smaller than min.
+func delegateLimit(cfg map[string]string, key string, def, min int) (int, error) {
+	raw := strings.TrimSpace(config.Lookup(cfg, key))
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < min {
+		return 0, fmt.Errorf("%s must be an integer of at least %d, got %q", key, min, raw)
+	}
+	return n, nil
+}
+
+// delegate returns the session configuration for d, or nil when d is at the
+// depth limit or has no agents to offer.
+func (d *delegator) delegate() *session.Delegate {
+	if d.depth >= d.maxDepth || len(d.agents) == 0 {
+		return nil
+	}
+	agents := make([]session.DelegateAgent, len(d.agents))
+	for i, ag := range d.agents {
+		agents[i] = session.DelegateAgent{Name: ag.Name, Description: ag.Description}
+	}
+	return &session.Delegate{Agents: agents, Run: d.run}
+}
+
+// run executes task in a nested session with the named agent's prompt,
+// config and tools, and returns the agent's final answer.  Its events go to
+// a nested sink labelled with the agent name.  Of the cli overrides only the
+// connection settings apply, so the lead's --model or --temperature do not
+// replace the delegated agent's own.
+func (d *delegator) run(ctx context.Context, name, task string) (string, error) {
+	var ag agent.Agent
+	found := false
+	for _, a := range d.agents {
+		if a.Name == name {
+			ag, found = a, true
+			break
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("agent %q not found in .rai/agents", name)
+	}
+
+	merged, err := config.LoadMerged(d.baseDir, ag.Config, connectionOverrides(d.p), map[string]string{})
+	if err != nil {
+		return "", fmt.Errorf("agent %s: %w", name, err)
+	}
+	genOpts, err := provider.ParseGenerationOptions(merged)
+	if err != nil {
+		return "", fmt.Errorf("agent %s: %w", name, err)
+	}
+	sub := d.p
+	sub.AgentPath = ag.Path
+	if _, err := renderAgent(sub, &ag, templateVars(sub, ag, merged, d.baseDir), merged, d.baseDir); err != nil {
+		return "", fmt.Errorf("agent %s: %w", name, err)
+	}
+
+	sink := d.sink.Nested(name)
+	prov, err := resolveProvider(merged, d.p.LogLevel, sink, d.baseDir)
+	if err != nil {
+		return "", fmt.Errorf("agent %s: %w", name, err)
+	}
+	discovered, err := agentSkills(ag, d.baseDir, sink)
+	if err != nil {
+		return "", fmt.Errorf("agent %s: %w", name, err)
+	}
+
+	child := *d
+	child.sink = sink
+	child.depth++
+	messages, err := session.RunConversation(ctx, session.Config{
+		Provider:      prov,
+		Sink:          sink,
+		SystemPrompt:  ag.SystemPrompt,
+		UserPrompt:    task,
+		Skills:        discovered,
+		Tools:         ag.Tools,
+		DisableTools:  ag.DisableTools,
+		BaseDir:       d.baseDir,
+		Options:       genOpts,
+		Delegate:      child.delegate(),
+		MaxIterations: d.maxIterations,
+	})
+	if err != nil {
+		return "", fmt.Errorf("agent %s: %w", name, err)
+	}
+	return finalAnswer(messages), nil
+}
+
+// connectionKeys are the cli overrides a delegated session inherits: how to
+// reach the provider, which the agent file does not normally say.  Model and
+// generation settings come from the delegated agent's own config.
+var connectionKeys = map[string]bool{
+	"provider": true, "endpoint": true, "api-key": true, "copilot-token": true, "enterprise-url": true,
+}
+
+// connectionOverrides returns the connection settings from the cli layer.
+func connectionOverrides(p Parsed) map[string]string {
+	out := map[string]string{}
+	for key, value := range p.Overrides {
+		if connectionKeys[strings.ReplaceAll(key, "_", "-")] {
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// finalAnswer returns the content of the last assistant message.
+func finalAnswer(messages []provider.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" && messages[i].Content != "" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,324 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"run-ai/internal/agent"
+	"run-ai/internal/config"
+	"run-ai/internal/provider"
+	"run-ai/internal/skills"
+)
+
+// Doctor check statuses.  Only fail makes `rai doctor` exit non-zero.
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// doctorRequestTimeout bounds the test request so a hanging endpoint cannot
+// stall the report.
+const doctorRequestTimeout = 30 * time.Second
+
+// doctorCheck is one line of the doctor report.
+type doctorCheck struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Guidance  string `json:"guidance,omitempty"`
+	LatencyMS int64  `json:"latency_ms,omitempty"`
+}
+
+// doctorReport is the full result, also the shape of `rai doctor --json`.
+type doctorReport struct {
+	OK     bool          `json:"ok"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+func (r *doctorReport) add(c doctorCheck) {
+	r.Checks = append(r.Checks, c)
+}
+
+// runDoctor implements `rai doctor [--json] [--no-request]`.  It honors
+// --agent and the override flags so a failing invocation can be diagnosed
+// with the same arguments.
+func runDoctor(p Parsed, stdout, stderr io.Writer, baseDir string) int {
+	var asJSON, noRequest bool
+	for _, arg := range p.SubArgs {
+		switch arg {
+		case "--json":
+			asJSON = true
+		case "--no-request":
+			noRequest = true
+		default:
+			writeUsage(stderr)
+			return 2
+		}
+	}
+
+	var report doctorReport
+	prov := doctorProvider(p, baseDir, &report)
+	if prov == nil {
+		report.add(doctorCheck{Name: "request", Status: checkSkip, Detail: "no provider"})
+	} else if noRequest {
+		report.add(doctorCheck{Name: "request", Status: checkSkip, Detail: "--no-request"})
+	} else {
+		report.add(doctorTestRequest(prov))
+	}
+	for _, c := range doctorAgents(p, baseDir) {
+		report.add(c)
+	}
+	for _, c := range doctorSkills(baseDir) {
+		report.add(c)
+	}
+
+	report.OK = true
+	for _, c := range report.Checks {
+		if c.Status == checkFail {
+			report.OK = false
+		}
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(stderr, "doctor error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, string(data))
+	} else {
+		fmt.Fprint(stdout, formatDoctorReport(report))
+	}
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// doctorProvider checks config parsing, provider resolution and credentials.
+// It returns the resolved provider, or nil on failure.
+func doctorProvider(p Parsed, baseDir string, report *doctorReport) provider.Provider {
+	fileValues, err := config.Load(baseDir)
+	if err != nil {
+		report.add(doctorCheck{
+			Name:     "config",
+			Status:   checkFail,
+			Detail:   err.Error(),
+			Guidance: fmt.Sprintf("fix or remove %s", config.ConfigPath(baseDir)),
+		})
+		return nil
+	}
+	if len(fileValues) == 0 {
+		report.add(doctorCheck{Name: "config", Status: checkPass, Detail: "no values in .rai/config (using environment and flags)"})
+	} else {
+		report.add(doctorCheck{Name: "config", Status: checkPass, Detail: fmt.Sprintf("%d value(s) in .rai/config", len(fileValues))})
+	}
+
+	resolved, err := resolvedConfig(p, baseDir)
+	if err != nil {
+		report.add(doctorCheck{Name: "provider", Status: checkFail, Detail: err.Error()})
+		return nil
+	}
+	merged := map[string]string{}
+	for key, r := range resolved {
+		merged[key] = r.Value
+	}
+
+	credentials := doctorCredentials(merged, resolved, baseDir)
+	loadCopilotToken(merged, baseDir)
+
+	prov, err := provider.Resolve(merged)
+	if err != nil {
+		report.add(doctorCheck{Name: "provider", Status: checkFail, Detail: err.Error(), Guidance: resolveGuidance(err)})
+		report.add(credentials)
+		return nil
+	}
+	model := merged["model"]
+	if model == "" {
+		model = "not set"
+		if def := provider.DefaultModel(merged); def != "" {
+			model = def + " (default)"
+		}
+	}
+	detail := fmt.Sprintf("%s, model %s", prov.Name(), model)
+	if endpoint := merged["endpoint"]; endpoint != "" {
+		detail += ", endpoint " + endpoint
+	}
+	report.add(doctorCheck{Name: "provider", Status: checkPass, Detail: detail})
+	report.add(credentials)
+	return prov
+}
+
+// doctorCredentials reports where the API key or Copilot token comes from,
+// without revealing it.
+func doctorCredentials(merged map[string]string, resolved map[string]config.Resolved, baseDir string) doctorCheck {
+	c := doctorCheck{Name: "credentials"}
+	for _, key := range []string{"api-key", "api_key", "copilot-token"} {
+		if r, ok := resolved[key]; ok && r.Value != "" {
+			c.Status = checkPass
+			c.Detail = fmt.Sprintf("%s %s (%s)", key, config.MaskSecret(r.Value), r.Source)
+			return c
+		}
+	}
+	provID := merged["provider"]
+	if provID == "github-copilot" || provID == "github-copilot-enterprise" {
+		if tok := provider.LoadCopilotToken(baseDir); tok != "" {
+			c.Status = checkPass
+			c.Detail = fmt.Sprintf("stored Copilot token %s (.rai/copilot-token)", config.MaskSecret(tok))
+			return c
+		}
+		c.Status = checkFail
+		c.Detail = "no Copilot token"
+		c.Guidance = "run 'rai copilot-login'"
+		return c
+	}
+	c.Status = checkFail
+	c.Detail = "no api-key configured"
+	c.Guidance = "set it with 'rai config api-key <key>' or RAI_API_KEY"
+	return c
+}
+
+// resolveGuidance suggests a fix for a provider.Resolve error.
+func resolveGuidance(err error) string {
+	switch {
+	case errors.Is(err, provider.ErrNoProvider):
+		return "set 'rai config endpoint <url>' or 'rai config provider github-copilot'"
+	case errors.Is(err, provider.ErrModelRequired):
+		return "set 'rai config model <name>'; 'rai models' lists the available models"
+	}
+	return ""
+}
+
+// doctorTestRequest sends a minimal prompt and reports its latency.
+func doctorTestRequest(prov provider.Provider) doctorCheck {
+	c := doctorCheck{Name: "request"}
+	ctx, cancel := context.WithTimeout(context.Background(), doctorRequestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := prov.Complete(ctx, provider.Request{
+		Messages: []provider.Message{{Role: "user", Content: "Reply with the single word OK."}},
+	})
+	c.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		c.Status = checkFail
+		var pe *provider.ProviderError
+		if errors.As(err, &pe) {
+			c.Detail = fmt.Sprintf("%s (HTTP %d)", pe.Message, pe.StatusCode)
+			c.Guidance = pe.Guidance
+		} else {
+			c.Detail = err.Error()
+		}
+		return c
+	}
+	c.Status = checkPass
+	c.Detail = fmt.Sprintf("%d ms", c.LatencyMS)
+	if strings.TrimSpace(resp.Content) == "" && len(resp.ToolCalls) == 0 {
+		c.Status = checkWarn
+		c.Detail += ", empty response"
+	}
+	return c
+}
+
+// doctorAgents lints the --agent file, every agents/*.md file and the named
+// agents in .rai/agents/.
+func doctorAgents(p Parsed, baseDir string) []doctorCheck {
+	var checks []doctorCheck
+	paths, _ := filepath.Glob(filepath.Join(baseDir, "agents", "*.md"))
+	library, _ := filepath.Glob(filepath.Join(agent.AgentsDir(baseDir), "*.md"))
+	paths = append(paths, library...)
+	if p.AgentPath != "" {
+		path, err := agent.ResolvePath(baseDir, p.AgentPath)
+		if err != nil {
+			checks = append(checks, doctorCheck{Name: "agent " + p.AgentPath, Status: checkFail, Detail: err.Error()})
+		} else {
+			paths = append([]string{path}, paths...)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, path := range paths {
+		abs, _ := filepath.Abs(path)
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		checks = append(checks, lintAgentFile(path, baseDir))
+	}
+	return checks
+}
+
+// lintAgentFile reports parse errors as failures and warnings (unknown keys,
+// ...) as warnings.
+func lintAgentFile(path, baseDir string) doctorCheck {
+	name := path
+	if rel, err := filepath.Rel(baseDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		name = rel
+	}
+	c := doctorCheck{Name: "agent " + filepath.ToSlash(name)}
+	if _, err := os.Stat(path); err != nil {
+		c.Status = checkFail
+		c.Detail = err.Error()
+		return c
+	}
+	ag, err := agent.Load(path, baseDir)
+	if err != nil {
+		c.Status = checkFail
+		c.Detail = err.Error()
+		return c
+	}
+	if len(ag.Warnings) > 0 {
+		c.Status = checkWarn
+		c.Detail = strings.Join(ag.Warnings, "; ")
+		return c
+	}
+	c.Status = checkPass
+	return c
+}
+
+// doctorSkills reports discovered skills and every skill that failed to load.
+func doctorSkills(baseDir string) []doctorCheck {
+	discovered, issues, err := skills.Discover(baseDir)
+	if err != nil {
+		return []doctorCheck{{Name: "skills", Status: checkFail, Detail: err.Error()}}
+	}
+	checks := []doctorCheck{{Name: "skills", Status: checkPass, Detail: fmt.Sprintf("%d skill(s) in .rai/skills", len(discovered))}}
+	for _, i := range issues {
+		checks = append(checks, doctorCheck{Name: "skills", Status: checkWarn, Detail: i.String()})
+	}
+	return checks
+}
+
+// formatDoctorReport renders the report as one line per check, with
+// guidance indented below, and a summary line.
+func formatDoctorReport(r doctorReport) string {
+	var b strings.Builder
+	counts := map[string]int{}
+	for _, c := range r.Checks {
+		counts[c.Status]++
+		fmt.Fprintf(&b, "[%s] %s", strings.ToUpper(c.Status), c.Name)
+		if c.Detail != "" {
+			fmt.Fprintf(&b, ": %s", c.Detail)
+		}
+		b.WriteString("\n")
+		if c.Guidance != "" {
+			fmt.Fprintf(&b, "       %s\n", c.Guidance)
+		}
+	}
+	result := "all checks passed"
+	if !r.OK {
+		result = "some checks failed"
+	}
+	fmt.Fprintf(&b, "\n%s (%d passed, %d warnings, %d failed, %d skipped)\n",
+		result, counts[checkPass], counts[checkWarn], counts[checkFail], counts[checkSkip])
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"run-ai/internal/config"
+	"run-ai/internal/provider"
+)
+
+// runModels implements `rai models [--json] [--refresh]`.  The list is served
+// from .rai/cache/ for a day unless --refresh is given.
+func runModels(p Parsed, stdout, stderr io.Writer, baseDir string) int {
+	var asJSON, refresh bool
+	for _, arg := range p.SubArgs {
+		switch arg {
+		case "--json":
+			asJSON = true
+		case "--refresh":
+			refresh = true
+		default:
+			writeUsage(stderr)
+			return 2
+		}
+	}
+
+	merged, err := config.LoadMerged(baseDir, nil, cliOverrides(p), map[string]string{})
+	if err != nil {
+		fmt.Fprintf(stderr, "config error: %v\n", err)
+		return 1
+	}
+	loadCopilotToken(merged, baseDir)
+
+	lister, err := provider.ResolveModelLister(merged)
+	if err != nil {
+		fmt.Fprintf(stderr, "provider error: %v\n", err)
+		return 1
+	}
+	models, err := loadModels(lister, merged, baseDir, refresh, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "models error: %v\n", err)
+		return 1
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(models, "", "  ")
+		if err != nil {
+			fmt.Fprintf(stderr, "models error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, string(data))
+		return 0
+	}
+	fmt.Fprint(stdout, formatModels(models, merged["model"]))
+	return 0
+}
+
+// loadModels returns the provider's models from .rai/cache/, fetching and
+// caching them when the cache is missing, expired or refresh is set.
+func loadModels(lister provider.ModelLister, merged map[string]string, baseDir string, refresh bool, stderr io.Writer) ([]provider.ModelInfo, error) {
+	name := merged["provider"]
+	if prov, ok := lister.(provider.Provider); ok {
+		name = prov.Name()
+	}
+	endpoint := strings.TrimSpace(merged["endpoint"])
+	if url := strings.TrimSpace(merged["enterprise-url"]); url != "" {
+		endpoint = url
+	}
+
+	account := provider.CredentialHash(merged)
+
+	if !refresh {
+		if cache, ok := provider.LoadModelCache(baseDir, name, endpoint, account, time.Now()); ok {
+			return cache.Models, nil
+		}
+	}
+	models, err := lister.ListModels(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	cache := provider.ModelCache{Provider: name, Endpoint: endpoint, Account: account, Fetched: time.Now(), Models: models}
+	if err := provider.SaveModelCache(baseDir, cache); err != nil {
+		fmt.Fprintf(stderr, "warning: could not cache models: %v\n", err)
+	}
+	return models, nil
+}
+
+// checkImageSupport rejects --image for a model known to lack vision.
+// OpenAI, Anthropic and Gemini models are looked up in the provider package's
+// table; Copilot reports vision in its (cached) model list, and a failure to
+// list is a warning rather than an error.  Unknown models are let through.
+func checkImageSupport(prov provider.Provider, merged map[string]string, baseDir string, stderr io.Writer) error {
+	model := strings.TrimSpace(merged["model"])
+	if model == "" {
+		return nil
+	}
+	if vision := provider.KnownVision(prov.Name(), model); vision != nil {
+		if !*vision {
+			return noVisionError(model)
+		}
+		return nil
+	}
+	lister, ok := prov.(provider.ModelLister)
+	if !ok || prov.Name() != "github-copilot" {
+		return nil
+	}
+	models, err := loadModels(lister, merged, baseDir, false, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: could not check image support for %s: %v\n", model, err)
+		return nil
+	}
+	for _, m := range models {
+		if m.ID == model && m.Vision != nil && !*m.Vision {
+			return noVisionError(model)
+		}
+	}
+	return nil
+}
+
+func noVisionError(model string) error {
+	return fmt.Errorf("model %s does not accept image input; 'rai models --json' shows each model's vision support", model)
+}
+
+// formatModels renders models as a table, marking the configured model.
+func formatModels(models []provider.ModelInfo, current string) string {
+	if len(models) == 0 {
+		return "no models available\n"
+	}
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+
+	showEndpoints := false
+	for _, m := range models {
+		if len(m.Endpoints) > 0 {
+			showEndpoints = true
+		}
+	}
+	if showEndpoints {
+		fmt.Fprintln(w, "  MODEL\tNAME\tAPI")
+	} else {
+		fmt.Fprintln(w, "  MODEL\tNAME")
+	}
+	for _, m := range models {
+		marker := " "
+		if m.ID == current {
+			marker = "*"
+		}
+		if showEndpoints {
+			fmt.Fprintf(w, "%s %s\t%s\t%s\n", marker, m.ID, m.Name, strings.Join(m.Endpoints, ","))
+		} else {
+			fmt.Fprintf(w, "%s %s\t%s\n", marker, m.ID, m.Name)
+		}
+	}
+	w.Flush()
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"run-ai/internal/session"
+)
+
+// runSessions implements `rai sessions list|show <id>|delete <id>`.
+func runSessions(args []string, stdout, stderr io.Writer, baseDir string) int {
+	if len(args) == 0 {
+		writeUsage(stderr)
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		records, err := session.ListRecords(baseDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "sessions error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, formatSessionList(records))
+		return 0
+	case "show":
+		if len(args) != 2 {
+			writeUsage(stderr)
+			return 2
+		}
+		rec, err := session.LoadRecord(baseDir, args[1])
+		if err != nil {
+			fmt.Fprintf(stderr, "sessions error: %v\n", err)
+			return 1
+		}
+		fmt.Fprint(stdout, formatSession(rec))
+		return 0
+	case "delete":
+		if len(args) != 2 {
+			writeUsage(stderr)
+			return 2
+		}
+		if err := session.DeleteRecord(baseDir, args[1]); err != nil {
+			fmt.Fprintf(stderr, "sessions error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "session deleted")
+		return 0
+	default:
+		writeUsage(stderr)
+		return 2
+	}
+}
+
+func formatSessionList(records []session.Record) string {
+	if len(records) == 0 {
+		return "no sessions found"
+	}
+	var b strings.Builder
+	for i, rec := range records {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(fmt.Sprintf("%s  %s  %d messages", rec.ID, rec.Updated.Format("2006-01-02 15:04:05"), len(rec.Messages)))
+		if first := firstUserPrompt(rec); first != "" {
+			b.WriteString("\n  " + first)
+		}
+	}
+	return b.String()
+}
+
+// firstUserPrompt returns the opening user turn, shortened to one line.
+func firstUserPrompt(rec session.Record) string {
+	for _, m := range rec.Messages {
+		if m.Role != "user" {
+			continue
+		}
+		line := strings.TrimSpace(strings.SplitN(m.Content, "\n", 2)[0])
+		if r := []rune(line); len(r) > 72 {
+			line = string(r[:69]) + "..."
+		}
+		return line
+	}
+	return ""
+}
+
+func formatSession(rec session.Record) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("id: %s\n", rec.ID))
+	b.WriteString(fmt.Sprintf("created: %s\n", rec.Created.Format("2006-01-02 15:04:05")))
+	b.WriteString(fmt.Sprintf("updated: %s\n", rec.Updated.Format("2006-01-02 15:04:05")))
+	if rec.Agent != "" {
+		b.WriteString(fmt.Sprintf("agent: %s\n", rec.Agent))
+	}
+	if rec.Model != "" {
+		b.WriteString(fmt.Sprintf("model: %s\n", rec.Model))
+	}
+	for _, m := range rec.Messages {
+		b.WriteString(fmt.Sprintf("\n--- %s ---\n", m.Role))
+		if m.Content != "" {
+			b.WriteString(m.Content)
+			if !strings.HasSuffix(m.Content, "\n") {
+				b.WriteString("\n")
+			}
+		}
+		for _, tc := range m.ToolCalls {
+			b.WriteString(fmt.Sprintf("tool call: %s(%s)\n", tc.Name, tc.Arguments))
+		}
+	}
+	return b.String()
+}
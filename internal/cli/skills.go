@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"run-ai/internal/skills"
+)
+
+// runSkills implements `rai skills list`, `rai skills validate [--json]`,
+// `rai skills show <name>` and `rai skills run <name> <script> [args...]`.
+func runSkills(args []string, stdout, stderr io.Writer, baseDir string) int {
+	switch {
+	case len(args) == 2 && args[0] == "show":
+		s, err := findSkill(args[1], baseDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "skills error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, skills.FormatShow(s))
+		return 0
+	case len(args) >= 3 && args[0] == "run":
+		return runSkillScript(args[1], args[2], args[3:], stdout, stderr, baseDir)
+	case len(args) == 1 && args[0] == "list":
+		discovered, issues, err := skills.Discover(baseDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "skills error: %v\n", err)
+			return 1
+		}
+		for _, i := range issues {
+			fmt.Fprintf(stderr, "%s: %s\n", i.Severity, i)
+		}
+		fmt.Fprintln(stdout, skills.FormatList(discovered))
+		return 0
+	case len(args) >= 1 && args[0] == "validate":
+		asJSON := false
+		for _, arg := range args[1:] {
+			if arg != "--json" {
+				writeUsage(stderr)
+				return 2
+			}
+			asJSON = true
+		}
+		return runSkillsValidate(asJSON, stdout, stderr, baseDir)
+	}
+	writeUsage(stderr)
+	return 2
+}
+
+// skillsReport is the shape of `rai skills validate --json`.
+type skillsReport struct {
+	OK     bool           `json:"ok"`
+	Skills []string       `json:"skills"`
+	Issues []skills.Issue `json:"issues"`
+}
+
+// runSkillsValidate checks every skill directory against the agentskills.io
+// specification and prints one path line per issue.  It fails when any
+// skill has an error, since such skills are not loaded.
+func runSkillsValidate(asJSON bool, stdout, stderr io.Writer, baseDir string) int {
+	discovered, issues, err := skills.Discover(baseDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "skills error: %v\n", err)
+		return 1
+	}
+	report := skillsReport{OK: true, Skills: []string{}, Issues: []skills.Issue{}}
+	for _, s := range discovered {
+		report.Skills = append(report.Skills, s.Name)
+	}
+	var errs, warnings int
+	for _, i := range issues {
+		i.Path = displayPath(i.Path, baseDir)
+		report.Issues = append(report.Issues, i)
+		if i.Severity == skills.SeverityError {
+			errs++
+			report.OK = false
+		} else {
+			warnings++
+		}
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(stderr, "skills error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, string(data))
+	} else {
+		for _, i := range report.Issues {
+			fmt.Fprintf(stdout, "%s: %s: %s\n", i.Path, i.Severity, i.Message)
+		}
+		if errs == 0 && warnings == 0 {
+			fmt.Fprintf(stdout, "%d skill(s) ok\n", len(discovered))
+		} else {
+			fmt.Fprintf(stdout, "%d error(s), %d warning(s)\n", errs, warnings)
+		}
+	}
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// displayPath shortens path to be relative to baseDir when it lies inside it.
+func displayPath(path, baseDir string) string {
+	if rel, err := filepath.Rel(baseDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// findSkill returns the discovered skill called name.
+func findSkill(name, baseDir string) (skills.Skill, error) {
+	discovered, _, err := skills.Discover(baseDir)
+	if err != nil {
+		return skills.Skill{}, err
+	}
+	found, err := skills.Select(discovered, []string{name})
+	if err != nil {
+		return skills.Skill{}, err
+	}
+	return found[0], nil
+}
+
+// runSkillScript implements `rai skills run`.  ParseArgs passes rest
+// through verbatim: only --json-args is recognised here, and everything
+// after a "--" goes to the script as is.  It builds the tool-call arguments
+// the model would send, then validates and executes them the way the
+// session runner does: same argument checks, working directory (the project
+// root), timeout and stdin.  The script's stdout and stderr are passed
+// through and its exit code becomes rai's.
+func runSkillScript(name, script string, rest []string, stdout, stderr io.Writer, baseDir string) int {
+	var args []string
+	jsonArgs := ""
+	for i := 0; i < len(rest); i++ {
+		switch {
+		case rest[i] == "--":
+			args = append(args, rest[i+1:]...)
+			i = len(rest)
+		case rest[i] == "--json-args" && i+1 < len(rest):
+			i++
+			jsonArgs = rest[i]
+		case strings.HasPrefix(rest[i], "--json-args="):
+			jsonArgs = strings.TrimPrefix(rest[i], "--json-args=")
+		default:
+			args = append(args, rest[i])
+		}
+	}
+
+	s, err := findSkill(name, baseDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "skills error: %v\n", err)
+		return 1
+	}
+	scripts, err := skills.Scripts(s)
+	if err != nil {
+		fmt.Fprintf(stderr, "skills error: %v\n", err)
+		return 1
+	}
+	script = scriptPath(script, scripts)
+	raw, err := skillCallArguments(s, script, scripts, args, jsonArgs)
+	if err != nil {
+		fmt.Fprintf(stderr, "skills error: %v\n", err)
+		return 2
+	}
+	call, err := skills.ParseCall(s, scripts, raw)
+	if err != nil {
+		fmt.Fprintf(stderr, "skills error: %v\n", err)
+		return 2
+	}
+	if call.Script == "" {
+		fmt.Fprintf(stderr, "skills error: skill %s has no scripts\n", s.Name)
+		return 1
+	}
+
+	res, err := skills.ExecuteCall(s, call, baseDir)
+	fmt.Fprint(stdout, res.Stdout)
+	fmt.Fprint(stderr, res.Stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "skills error: %v\n", err)
+		return 1
+	}
+	return res.ExitCode
+}
+
+// scriptPath accepts a script named without its scripts/ prefix.
+func scriptPath(script string, scripts []string) string {
+	for _, s := range scripts {
+		if s == script {
+			return script
+		}
+	}
+	for _, s := range scripts {
+		if s == "scripts/"+script {
+			return s
+		}
+	}
+	return script
+}
+
+// skillCallArguments returns the tool-call arguments JSON for a manual run.
+// Skills with declared parameters take their input from --json-args;
+// others take the script's command-line arguments.
+func skillCallArguments(s skills.Skill, script string, scripts, args []string, jsonArgs string) (string, error) {
+	if s.Parameters == nil {
+		if jsonArgs != "" {
+			return "", fmt.Errorf("skill %s declares no parameters; pass command-line arguments instead of --json-args", s.Name)
+		}
+		if args == nil {
+			args = []string{}
+		}
+		data, err := json.Marshal(map[string]interface{}{"script": script, "args": args})
+		return string(data), err
+	}
+
+	if len(args) > 0 {
+		return "", fmt.Errorf("skill %s takes JSON input; pass it with --json-args", s.Name)
+	}
+	if jsonArgs == "" {
+		jsonArgs = "{}"
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonArgs), &values); err != nil || values == nil {
+		return "", fmt.Errorf("--json-args must be a JSON object")
+	}
+	switch len(scripts) {
+	case 0:
+	case 1:
+		if script != scripts[0] {
+			return "", fmt.Errorf("skill %s has no script %s (available: %s)", s.Name, script, scripts[0])
+		}
+	default:
+		values["script"] = script
+	}
+	data, err := json.Marshal(values)
+	return string(data), err
+}
@@ -84,6 +84,24 @@ func Set(baseDir, key, value string) error {
 	return save(baseDir, values)
 }
 
+// Unset removes key from the local config file and reports whether it was set.
+func Unset(baseDir, key string) (bool, error) {
+	if strings.TrimSpace(key) == "" {
+		return false, errors.New("config key cannot be empty")
+	}
+
+	values, err := Load(baseDir)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := values[key]; !ok {
+		return false, nil
+	}
+	delete(values, key)
+
+	return true, save(baseDir, values)
+}
+
 func save(baseDir string, values map[string]string) error {
 	configDir := filepath.Join(baseDir, configDirName)
 	if err := os.MkdirAll(configDir, 0o755); err != nil {
@@ -117,3 +117,80 @@ func TestLoadMerged(t *testing.T) {
 		t.Fatalf("expected model to be cli, got %q", merged["model"])
 	}
 }
+
+func TestLookupUnderscoreFallback(t *testing.T) {
+	values := map[string]string{"max_tokens": "100", "model": "m"}
+	if got := Lookup(values, "max-tokens"); got != "100" {
+		t.Fatalf("expected underscore fallback, got %q", got)
+	}
+	if got := Lookup(values, "model"); got != "m" {
+		t.Fatalf("expected direct lookup, got %q", got)
+	}
+	values["max-tokens"] = "200"
+	if got := Lookup(values, "max-tokens"); got != "200" {
+		t.Fatalf("expected hyphenated key to win, got %q", got)
+	}
+}
+
+func TestIsSecret(t *testing.T) {
+	for _, key := range []string{"api-key", "api_key", "copilot-token", "client-secret", "API_KEY"} {
+		if !IsSecret(key) {
+			t.Errorf("IsSecret(%q) = false, want true", key)
+		}
+	}
+	for _, key := range []string{"model", "max-tokens", "max_output_tokens", "endpoint", "keyword"} {
+		if IsSecret(key) {
+			t.Errorf("IsSecret(%q) = true, want false", key)
+		}
+	}
+}
+
+func TestMaskSecret(t *testing.T) {
+	if got := MaskSecret("sk-abcdefghijkl"); got != "sk-a****" {
+		t.Fatalf("MaskSecret = %q", got)
+	}
+	if got := MaskSecret("short"); got != "****" {
+		t.Fatalf("MaskSecret short = %q", got)
+	}
+	if got := MaskSecret(""); got != "" {
+		t.Fatalf("MaskSecret empty = %q", got)
+	}
+}
+
+func TestUnset(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := Set(tempDir, "model", "m"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	found, err := Unset(tempDir, "model")
+	if err != nil || !found {
+		t.Fatalf("Unset = %v, %v; want true, nil", found, err)
+	}
+	values, _ := Load(tempDir)
+	if _, ok := values["model"]; ok {
+		t.Fatalf("expected model removed, got %v", values)
+	}
+	found, err = Unset(tempDir, "model")
+	if err != nil || found {
+		t.Fatalf("second Unset = %v, %v; want false, nil", found, err)
+	}
+}
+
+func TestMergeSources(t *testing.T) {
+	merged := MergeSources(nil,
+		map[string]string{"model": "env", "endpoint": "env"},
+		map[string]string{"endpoint": "file"},
+		map[string]string{"model": "agent"},
+		map[string]string{"temperature": "0.1"},
+	)
+	want := map[string]Resolved{
+		"model":       {Value: "agent", Source: SourceAgent},
+		"endpoint":    {Value: "file", Source: SourceFile},
+		"temperature": {Value: "0.1", Source: SourceCLI},
+	}
+	for key, w := range want {
+		if merged[key] != w {
+			t.Errorf("merged[%q] = %+v, want %+v", key, merged[key], w)
+		}
+	}
+}
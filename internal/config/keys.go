@@ -0,0 +1,31 @@
+package config
+
+// knownKeys lists the config keys rai reads, in their hyphenated spelling.
+// Other keys are still accepted; this set drives shell completion.
+var knownKeys = []string{
+	"api-key",
+	"attach-max-bytes",
+	"copilot-token",
+	"delegate-max-depth",
+	"delegate-max-iterations",
+	"endpoint",
+	"enterprise-url",
+	"include-max-bytes",
+	"max-output-tokens",
+	"max-tokens",
+	"model",
+	"provider",
+	"reasoning-summary",
+	"schema-retries",
+	"stdin-max-bytes",
+	"temperature",
+	"tool-choice",
+	"top-p",
+}
+
+// KnownKeys returns the config keys rai reads, sorted.
+func KnownKeys() []string {
+	keys := make([]string, len(knownKeys))
+	copy(keys, knownKeys)
+	return keys
+}
@@ -1,5 +1,7 @@
 package config
 
+import "strings"
+
 // MergePrecedence merges config maps from lowest to highest precedence.
 func MergePrecedence(defaults, env, file, agent, cli map[string]string) map[string]string {
 	merged := map[string]string{}
@@ -18,6 +20,40 @@ func MergePrecedence(defaults, env, file, agent, cli map[string]string) map[stri
 	return merged
 }
 
+// Layer names reported by MergeSources, lowest precedence first.
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceAgent   = "agent"
+	SourceCLI     = "cli"
+)
+
+// Resolved is a merged config value together with the layer it came from.
+type Resolved struct {
+	Value  string
+	Source string
+}
+
+// MergeSources merges like MergePrecedence but records which layer supplied
+// each value, for `rai config list --resolved`.
+func MergeSources(defaults, env, file, agent, cli map[string]string) map[string]Resolved {
+	merged := map[string]Resolved{}
+	apply := func(values map[string]string, source string) {
+		for key, value := range values {
+			merged[key] = Resolved{Value: value, Source: source}
+		}
+	}
+
+	apply(defaults, SourceDefault)
+	apply(env, SourceEnv)
+	apply(file, SourceFile)
+	apply(agent, SourceAgent)
+	apply(cli, SourceCLI)
+
+	return merged
+}
+
 // LoadMerged loads .rai/config and merges it with env, agent, and CLI values.
 func LoadMerged(baseDir string, agent, cli, defaults map[string]string) (map[string]string, error) {
 	fileValues, err := Load(baseDir)
@@ -27,3 +63,13 @@ func LoadMerged(baseDir string, agent, cli, defaults map[string]string) (map[str
 
 	return MergePrecedence(defaults, EnvValues(), fileValues, agent, cli), nil
 }
+
+// Lookup returns the value for key, falling back to its underscore spelling
+// (e.g. "max-tokens" and "max_tokens") since RAI_* environment variables can
+// only produce the latter.
+func Lookup(values map[string]string, key string) string {
+	if v, ok := values[key]; ok {
+		return v
+	}
+	return values[strings.ReplaceAll(key, "-", "_")]
+}
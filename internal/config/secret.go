@@ -0,0 +1,32 @@
+package config
+
+import "strings"
+
+// IsSecret reports whether key holds a credential that should not be shown
+// in listings or logs (api-key, copilot-token, *-secret, ...).  Underscore
+// spellings are treated the same as hyphenated ones.
+func IsSecret(key string) bool {
+	k := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(key), "_", "-"))
+	switch k {
+	case "key", "token", "secret", "password":
+		return true
+	}
+	for _, suffix := range []string{"-key", "-token", "-secret", "-password"} {
+		if strings.HasSuffix(k, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSecret hides a secret value, keeping only a short prefix so users can
+// still tell which credential is configured.
+func MaskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return "****"
+	}
+	return value[:4] + "****"
+}
@@ -7,9 +7,18 @@
 //
 // Silent and Log can be combined: everything goes to the log, only the final
 // response and errors appear on the console.
+//
+// A nested session, such as a delegated sub-agent, writes through a sink from
+// Nested: the same console and log, with each event labelled and indented.
+//
+// With JSON (--output json) console events are written as newline-delimited
+// JSON objects (see JSONEvent) instead of [KIND]-prefixed text, so scripts can
+// consume them without screen-scraping.  Silent still applies, leaving only
+// errors and the final object.
 package output
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -34,16 +43,49 @@ const (
 	EventCMD       EventKind = "CMD"    // Terminal command being executed
 	EventOUT       EventKind = "OUT"    // Terminal command output
 	EventERR       EventKind = "ERR"    // Error or warning
+	EventUser      EventKind = "USER"   // User turn in interactive chat
+	EventFinal     EventKind = "FINAL"  // Final response (JSON output only)
 )
 
+// ToolInfo describes the tool call behind a CMD or OUT event.
+type ToolInfo struct {
+	Name      string
+	Arguments string // raw JSON arguments as sent by the model
+	// ExitCode is set on OUT events: the process exit status, 0 for success,
+	// or -1 when the tool failed without an exit status.
+	ExitCode *int
+}
+
+// JSONEvent is the stable schema of one NDJSON line in JSON output mode.
+type JSONEvent struct {
+	Kind      EventKind `json:"kind"`
+	Timestamp string    `json:"timestamp"` // RFC 3339 with fractional seconds
+	Text      string    `json:"text"`
+	Tool      *JSONTool `json:"tool,omitempty"`  // CMD, OUT and tool ERR events only
+	Agent     string    `json:"agent,omitempty"` // nested sessions only, e.g. "reviewer" or "reviewer/linter"
+}
+
+// JSONTool is the tool portion of a JSONEvent.
+type JSONTool struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments,omitempty"`
+	ExitCode  *int   `json:"exit_code,omitempty"`
+}
+
 // Sink receives output events and writes them to console and/or a log file.
 // All methods are safe for concurrent use.
 type Sink struct {
-	mu      sync.Mutex
+	mu      *sync.Mutex // shared with nested sinks
 	console io.Writer
 	logFile *os.File
 	silent  bool
+	json    bool
 	now     func() time.Time
+
+	// label and indent mark the events of a nested sink; both are empty for
+	// the top-level sink.
+	label  string
+	indent string
 }
 
 // Options configures how a Sink behaves.
@@ -52,6 +94,7 @@ type Options struct {
 	Log     bool      // Write all events to a log file in .rai/log/.
 	BaseDir string    // Working directory root (for .rai/log/).
 	Console io.Writer // Writer for console output (typically os.Stdout).
+	JSON    bool      // Write console events as NDJSON instead of prefixed text.
 
 	// Now overrides the clock for deterministic testing.  When nil time.Now is used.
 	Now func() time.Time
@@ -62,8 +105,10 @@ type Options struct {
 // is not writable.
 func NewSink(opts Options) (*Sink, error) {
 	s := &Sink{
+		mu:      &sync.Mutex{},
 		console: opts.Console,
 		silent:  opts.Silent,
+		json:    opts.JSON,
 		now:     opts.Now,
 	}
 	if s.now == nil {
@@ -131,30 +176,83 @@ func (s *Sink) WriteHeader(args map[string]string, agentContent, prompt string)
 // Emit writes an event to active outputs.
 // In silent mode only EventERR reaches the console; all events always reach the log.
 func (s *Sink) Emit(kind EventKind, text string) {
+	s.EmitTool(kind, text, ToolInfo{})
+}
+
+// EmitTool is Emit for CMD and OUT events, carrying the tool name, arguments
+// and exit status.  The tool details only appear in JSON output; text output
+// and the log show text exactly as Emit would.
+func (s *Sink) EmitTool(kind EventKind, text string, tool ToolInfo) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Console: show everything unless silent (errors always shown).
 	if !s.silent || kind == EventERR {
-		fmt.Fprintf(s.console, "[%s] %s\n", kind, text)
+		if s.json {
+			s.writeJSON(kind, text, tool)
+		} else {
+			fmt.Fprintf(s.console, "%s[%s] %s\n", s.prefix(), kind, s.indentLines(text))
+		}
 	}
 
 	// Log file: always record with timestamp.
-	if s.logFile != nil {
-		ts := s.now().Format("2006-01-02 15:04:05.000")
-		fmt.Fprintf(s.logFile, "[%s] [%s] %s\n", ts, kind, text)
-	}
+	s.writeLog(kind, text)
 }
 
 // EmitLog writes an event only to the log file, if logging is enabled.
 func (s *Sink) EmitLog(kind EventKind, text string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.writeLog(kind, text)
+}
 
+// writeLog appends one timestamped event to the log file, if any.  Callers
+// hold s.mu.
+func (s *Sink) writeLog(kind EventKind, text string) {
 	if s.logFile != nil {
 		ts := s.now().Format("2006-01-02 15:04:05.000")
-		fmt.Fprintf(s.logFile, "[%s] [%s] %s\n", ts, kind, text)
+		fmt.Fprintf(s.logFile, "[%s] %s[%s] %s\n", ts, s.prefix(), kind, s.indentLines(text))
+	}
+}
+
+// Nested returns a sink for a nested session, such as a delegated
+// sub-agent.  Its events go to the same console and log, labelled with
+// label (joined to any outer label with "/") and indented one level deeper.
+// AI text is written whole rather than streamed, and the final response is
+// only logged: it is a tool result for the outer session, not the answer.
+// Closing a nested sink does nothing.
+func (s *Sink) Nested(label string) *Sink {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.label != "" {
+		label = s.label + "/" + label
+	}
+	return &Sink{
+		mu:      s.mu,
+		console: s.console,
+		logFile: s.logFile,
+		silent:  s.silent,
+		json:    s.json,
+		now:     s.now,
+		label:   label,
+		indent:  s.indent + "  ",
+	}
+}
+
+// prefix is written before the [KIND] tag of a nested sink's events.
+func (s *Sink) prefix() string {
+	if s.label == "" {
+		return ""
+	}
+	return s.indent + "[" + s.label + "] "
+}
+
+// indentLines indents the continuation lines of a nested sink's text.
+func (s *Sink) indentLines(text string) string {
+	if s.indent == "" {
+		return text
 	}
+	return strings.ReplaceAll(strings.TrimRight(text, "\n"), "\n", "\n"+s.indent)
 }
 
 // BeginAIStream writes the AI prefix to the console for inline streaming.
@@ -162,7 +260,7 @@ func (s *Sink) BeginAIStream() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.silent {
+	if s.silent || s.json || s.label != "" {
 		return
 	}
 	fmt.Fprint(s.console, "[AI] ")
@@ -173,13 +271,15 @@ func (s *Sink) EmitAIChunk(text string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.silent {
+	if s.silent || s.json || s.label != "" {
 		return
 	}
 	fmt.Fprint(s.console, text)
 }
 
-// EndAIStream ensures the streamed AI output ends with a newline.
+// EndAIStream ensures the streamed AI output ends with a newline.  In JSON
+// mode chunks are not written individually; the whole message is emitted here
+// as a single AI event.
 func (s *Sink) EndAIStream(finalText string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -187,6 +287,14 @@ func (s *Sink) EndAIStream(finalText string) {
 	if s.silent {
 		return
 	}
+	if s.json {
+		s.writeJSON(EventAI, finalText, ToolInfo{})
+		return
+	}
+	if s.label != "" {
+		fmt.Fprintf(s.console, "%s[%s] %s\n", s.prefix(), EventAI, s.indentLines(finalText))
+		return
+	}
 	if !strings.HasSuffix(finalText, "\n") {
 		fmt.Fprintln(s.console)
 	}
@@ -198,22 +306,27 @@ func (s *Sink) EmitFinal(text string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	fmt.Fprint(s.console, text)
-	if !strings.HasSuffix(text, "\n") {
-		fmt.Fprintln(s.console)
+	if s.label != "" {
+		s.writeLog(EventAI, text)
+		return
 	}
-
-	if s.logFile != nil {
-		ts := s.now().Format("2006-01-02 15:04:05.000")
-		fmt.Fprintf(s.logFile, "[%s] [AI] %s\n", ts, text)
+	if s.json {
+		s.writeJSON(EventFinal, text, ToolInfo{})
+	} else {
+		fmt.Fprint(s.console, text)
+		if !strings.HasSuffix(text, "\n") {
+			fmt.Fprintln(s.console)
+		}
 	}
+
+	s.writeLog(EventAI, text)
 }
 
 // Close flushes and closes the log file.  It is safe to call multiple times.
 func (s *Sink) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if s.logFile != nil {
+	if s.logFile != nil && s.label == "" {
 		err := s.logFile.Close()
 		s.logFile = nil
 		return err
@@ -231,6 +344,31 @@ func (s *Sink) LogPath() string {
 	return s.logFile.Name()
 }
 
+// IsJSON reports whether console events are written as NDJSON.
+func (s *Sink) IsJSON() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.json
+}
+
+// writeJSON writes one NDJSON event to the console.  Callers hold s.mu.
+func (s *Sink) writeJSON(kind EventKind, text string, tool ToolInfo) {
+	ev := JSONEvent{
+		Kind:      kind,
+		Timestamp: s.now().Format(time.RFC3339Nano),
+		Text:      text,
+		Agent:     s.label,
+	}
+	if tool.Name != "" {
+		ev.Tool = &JSONTool{Name: tool.Name, Arguments: tool.Arguments, ExitCode: tool.ExitCode}
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	s.console.Write(append(data, '\n'))
+}
+
 // IsSilent reports whether the sink is configured for silent console output.
 func (s *Sink) IsSilent() bool {
 	s.mu.Lock()
@@ -2,6 +2,7 @@ package output
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -288,6 +289,123 @@ func TestSilentAndLogCombined(t *testing.T) {
 	}
 }
 
+// --- JSON output tests ---
+
+// decodeEvents parses NDJSON console output.
+func decodeEvents(t *testing.T, out string) []JSONEvent {
+	t.Helper()
+	var events []JSONEvent
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var ev JSONEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestJSONModeEmitsOneObjectPerEvent(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := NewSink(Options{Console: &buf, JSON: true, Now: nowFunc()})
+	if err != nil {
+		t.Fatalf("NewSink: %v", err)
+	}
+	defer sink.Close()
+
+	sink.BeginAIStream()
+	sink.EmitAIChunk("Hel")
+	sink.EmitAIChunk("lo")
+	sink.EndAIStream("Hello")
+	sink.EmitTool(EventCMD, "ls", ToolInfo{Name: "terminal", Arguments: `{"command":"ls"}`})
+	code := 2
+	sink.EmitTool(EventOUT, "missing", ToolInfo{Name: "terminal", ExitCode: &code})
+	sink.EmitFinal("done")
+
+	events := decodeEvents(t, buf.String())
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d:\n%s", len(events), buf.String())
+	}
+	if events[0].Kind != EventAI || events[0].Text != "Hello" {
+		t.Errorf("AI event = %+v", events[0])
+	}
+	if events[0].Timestamp != "2024-03-15T14:30:22Z" {
+		t.Errorf("timestamp = %q", events[0].Timestamp)
+	}
+	if events[1].Tool == nil || events[1].Tool.Name != "terminal" || events[1].Tool.Arguments != `{"command":"ls"}` {
+		t.Errorf("CMD event = %+v", events[1])
+	}
+	if events[2].Tool == nil || events[2].Tool.ExitCode == nil || *events[2].Tool.ExitCode != 2 {
+		t.Errorf("OUT event = %+v", events[2])
+	}
+	if events[3].Kind != EventFinal || events[3].Text != "done" {
+		t.Errorf("final event = %+v", events[3])
+	}
+}
+
+func TestJSONModeSilentOnlyFinalAndErrors(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := NewSink(Options{Console: &buf, JSON: true, Silent: true, Now: nowFunc()})
+	if err != nil {
+		t.Fatalf("NewSink: %v", err)
+	}
+	defer sink.Close()
+
+	sink.EndAIStream("thinking")
+	sink.Emit(EventCMD, "ls")
+	sink.Emit(EventERR, "oops")
+	sink.EmitFinal("answer")
+
+	events := decodeEvents(t, buf.String())
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d:\n%s", len(events), buf.String())
+	}
+	if events[0].Kind != EventERR || events[1].Kind != EventFinal || events[1].Text != "answer" {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
+func TestNestedSinkLabelsEvents(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := NewSink(Options{Console: &buf, Now: nowFunc()})
+	if err != nil {
+		t.Fatalf("NewSink: %v", err)
+	}
+	defer sink.Close()
+
+	inner := sink.Nested("reviewer")
+	inner.BeginAIStream()
+	inner.EmitAIChunk("Look")
+	inner.EndAIStream("Looks\nfine")
+	inner.Nested("linter").Emit(EventCMD, "go vet")
+	inner.EmitFinal("approved")
+	sink.EmitFinal("done")
+
+	want := "  [reviewer] [AI] Looks\n  fine\n" +
+		"    [reviewer/linter] [CMD] go vet\n" +
+		"done\n"
+	if got := buf.String(); got != want {
+		t.Errorf("console =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNestedSinkJSONCarriesAgent(t *testing.T) {
+	var buf bytes.Buffer
+	sink, _ := NewSink(Options{Console: &buf, JSON: true, Now: nowFunc()})
+	defer sink.Close()
+
+	sink.Nested("reviewer").Emit(EventCMD, "ls")
+	sink.Emit(EventCMD, "pwd")
+
+	events := decodeEvents(t, buf.String())
+	if len(events) != 2 || events[0].Agent != "reviewer" || events[1].Agent != "" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
+
 // --- Close idempotency ---
 
 func TestCloseIdempotent(t *testing.T) {
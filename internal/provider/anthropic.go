@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -24,8 +25,8 @@ func (p *anthropicProvider) Name() string { return "anthropic" }
 // --- Request/Response types ---
 
 type anthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // string, or []anthropicContentBlock for images
 }
 
 type anthropicToolDef struct {
@@ -41,15 +42,24 @@ type anthropicRequest struct {
 	MaxTokens   int                `json:"max_tokens"`
 	Stream      bool               `json:"stream,omitempty"`
 	Temperature *float64           `json:"temperature,omitempty"`
+	TopP        *float64           `json:"top_p,omitempty"`
 	Tools       []anthropicToolDef `json:"tools,omitempty"`
+	ToolChoice  json.RawMessage    `json:"tool_choice,omitempty"`
 }
 
 type anthropicContentBlock struct {
-	Type  string          `json:"type"`
-	Text  string          `json:"text,omitempty"`
-	ID    string          `json:"id,omitempty"`
-	Name  string          `json:"name,omitempty"`
-	Input json.RawMessage `json:"input,omitempty"`
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	ID     string                `json:"id,omitempty"`
+	Name   string                `json:"name,omitempty"`
+	Input  json.RawMessage       `json:"input,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"` // "base64"
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
 }
 
 type anthropicResponse struct {
@@ -227,7 +237,11 @@ func (p *anthropicProvider) buildRequest(req Request, stream bool) anthropicRequ
 			system = m.Content
 			continue
 		}
-		messages = append(messages, anthropicMessage{Role: m.Role, Content: m.Content})
+		msg := anthropicMessage{Role: m.Role, Content: m.Content}
+		if m.HasImages() {
+			msg.Content = anthropicBlocks(m.Parts)
+		}
+		messages = append(messages, msg)
 	}
 
 	// Ensure at least one message exists.
@@ -247,11 +261,20 @@ func (p *anthropicProvider) buildRequest(req Request, stream bool) anthropicRequ
 		MaxTokens:   maxTokens,
 		Stream:      stream,
 		Temperature: req.Temperature,
+		TopP:        req.TopP,
 	}
 
 	if req.Model != "" {
 		antReq.Model = req.Model
 	}
+	// The Messages API has no JSON Schema response mode; ask for it in the
+	// system prompt and rely on the caller's validation.
+	if len(req.ResponseSchema) > 0 {
+		if antReq.System != "" {
+			antReq.System += "\n\n"
+		}
+		antReq.System += schemaInstruction(req.ResponseSchema)
+	}
 
 	for _, t := range req.Tools {
 		antReq.Tools = append(antReq.Tools, anthropicToolDef{
@@ -260,10 +283,30 @@ func (p *anthropicProvider) buildRequest(req Request, stream bool) anthropicRequ
 			InputSchema: json.RawMessage(t.Parameters),
 		})
 	}
+	if len(req.Tools) > 0 {
+		antReq.ToolChoice = anthropicToolChoice(req.ToolChoice)
+	}
 
 	return antReq
 }
 
+// anthropicBlocks converts content parts to text and base64 image blocks.
+func anthropicBlocks(parts []ContentPart) []anthropicContentBlock {
+	var blocks []anthropicContentBlock
+	for _, part := range parts {
+		switch part.Type {
+		case PartText:
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: part.Text})
+		case PartImage:
+			blocks = append(blocks, anthropicContentBlock{
+				Type:   "image",
+				Source: &anthropicImageSource{Type: "base64", MediaType: part.MIMEType, Data: part.Data},
+			})
+		}
+	}
+	return blocks
+}
+
 func (p *anthropicProvider) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", p.apiKey)
@@ -286,3 +329,42 @@ func (p *anthropicProvider) parseResponse(resp anthropicResponse) Response {
 	}
 	return result
 }
+
+// --- Model listing ---
+
+// ListModels implements ModelLister via GET /v1/models, following pages.
+func (p *anthropicProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	var models []ModelInfo
+	afterID := ""
+	for {
+		query := url.Values{"limit": {"1000"}}
+		if afterID != "" {
+			query.Set("after_id", afterID)
+		}
+		endpoint := strings.TrimRight(p.endpoint, "/") + "/v1/models?" + query.Encode()
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		p.setHeaders(httpReq)
+
+		var resp struct {
+			Data []struct {
+				ID          string `json:"id"`
+				DisplayName string `json:"display_name"`
+			} `json:"data"`
+			HasMore bool   `json:"has_more"`
+			LastID  string `json:"last_id"`
+		}
+		if err := fetchJSON(&p.client, httpReq, normalizeFor("anthropic"), &resp); err != nil {
+			return nil, err
+		}
+		for _, m := range resp.Data {
+			models = append(models, ModelInfo{ID: m.ID, Name: m.DisplayName, Vendor: "anthropic", Vision: KnownVision("anthropic", m.ID)})
+		}
+		if !resp.HasMore || resp.LastID == "" || resp.LastID == afterID {
+			return sortModels(models), nil
+		}
+		afterID = resp.LastID
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -72,11 +73,19 @@ func (p *copilotProvider) Stream(ctx context.Context, req Request) (<-chan Strea
 
 type copilotChatMessage struct {
 	Role       string                `json:"role"`
-	Content    string                `json:"content"`
+	Content    interface{}           `json:"content"` // string, or []copilotChatPart for images
 	ToolCalls  []copilotChatToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string                `json:"tool_call_id,omitempty"`
 }
 
+type copilotChatPart struct {
+	Type     string `json:"type"` // "text" or "image_url"
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
 type copilotChatToolCall struct {
 	ID       string `json:"id,omitempty"`
 	Type     string `json:"type"`
@@ -98,12 +107,15 @@ type copilotChatFunction struct {
 }
 
 type copilotChatRequest struct {
-	Model       string               `json:"model"`
-	Messages    []copilotChatMessage `json:"messages"`
-	Stream      bool                 `json:"stream,omitempty"`
-	MaxTokens   int                  `json:"max_tokens,omitempty"`
-	Temperature *float64             `json:"temperature,omitempty"`
-	Tools       []copilotChatTool    `json:"tools,omitempty"`
+	Model          string               `json:"model"`
+	Messages       []copilotChatMessage `json:"messages"`
+	Stream         bool                 `json:"stream,omitempty"`
+	MaxTokens      int                  `json:"max_tokens,omitempty"`
+	Temperature    *float64             `json:"temperature,omitempty"`
+	TopP           *float64             `json:"top_p,omitempty"`
+	Tools          []copilotChatTool    `json:"tools,omitempty"`
+	ToolChoice     json.RawMessage      `json:"tool_choice,omitempty"`
+	ResponseFormat json.RawMessage      `json:"response_format,omitempty"`
 }
 
 type copilotChatChoice struct {
@@ -147,6 +159,7 @@ func (p *copilotProvider) completeChat(ctx context.Context, req Request) (Respon
 		return Response{}, err
 	}
 	p.setHeaders(httpReq)
+	setVisionHeader(httpReq, req)
 
 	httpResp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -190,6 +203,7 @@ func (p *copilotProvider) streamChat(ctx context.Context, req Request) (<-chan S
 		return nil, err
 	}
 	p.setHeaders(httpReq)
+	setVisionHeader(httpReq, req)
 
 	httpResp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -323,6 +337,9 @@ func (p *copilotProvider) buildChatRequest(req Request, stream bool) copilotChat
 			Role:    m.Role,
 			Content: m.Content,
 		}
+		if m.HasImages() {
+			msg.Content = copilotChatParts(m.Parts)
+		}
 		if m.ToolCallID != "" {
 			msg.ToolCallID = m.ToolCallID
 		}
@@ -343,6 +360,7 @@ func (p *copilotProvider) buildChatRequest(req Request, stream bool) copilotChat
 		Stream:      stream,
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
+		TopP:        req.TopP,
 	}
 	if req.Model != "" {
 		chatReq.Model = req.Model
@@ -358,9 +376,32 @@ func (p *copilotProvider) buildChatRequest(req Request, stream bool) copilotChat
 			},
 		})
 	}
+	if len(req.Tools) > 0 {
+		chatReq.ToolChoice = openAIToolChoice(req.ToolChoice, true)
+	}
+	chatReq.ResponseFormat = chatResponseFormat(req.ResponseSchema)
 	return chatReq
 }
 
+// copilotChatParts converts content parts to Chat API text and image_url
+// parts, with images inlined as data: URLs.
+func copilotChatParts(parts []ContentPart) []copilotChatPart {
+	var out []copilotChatPart
+	for _, part := range parts {
+		switch part.Type {
+		case PartText:
+			out = append(out, copilotChatPart{Type: "text", Text: part.Text})
+		case PartImage:
+			cp := copilotChatPart{Type: "image_url"}
+			cp.ImageURL = &struct {
+				URL string `json:"url"`
+			}{URL: part.DataURL()}
+			out = append(out, cp)
+		}
+	}
+	return out
+}
+
 func (p *copilotProvider) parseChatResponse(resp copilotChatResponse) Response {
 	var result Response
 	if len(resp.Choices) == 0 {
@@ -401,6 +442,7 @@ func (p *copilotProvider) completeResponses(ctx context.Context, req Request) (R
 		return Response{}, err
 	}
 	p.setHeaders(httpReq)
+	setVisionHeader(httpReq, req)
 
 	httpResp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -441,6 +483,7 @@ func (p *copilotProvider) streamResponses(ctx context.Context, req Request) (<-c
 		return nil, err
 	}
 	p.setHeaders(httpReq)
+	setVisionHeader(httpReq, req)
 
 	httpResp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -555,17 +598,13 @@ func (p *copilotProvider) readResponsesSSE(ctx context.Context, body io.Reader,
 // --- Responses helpers ---
 
 func (p *copilotProvider) buildResponsesRequest(req Request, stream bool) openAIRequest {
-	var input []openAIInput
-	for _, m := range req.Messages {
-		input = append(input, openAIInput{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID})
-	}
-
 	oaiReq := openAIRequest{
 		Model:       p.model,
-		Input:       input,
+		Input:       openAIInputs(req.Messages),
 		Stream:      stream,
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
+		TopP:        req.TopP,
 	}
 	if p.reasoningSummary != "" {
 		oaiReq.Reasoning = &openAIReasoning{Summary: p.reasoningSummary}
@@ -585,6 +624,10 @@ func (p *copilotProvider) buildResponsesRequest(req Request, stream bool) openAI
 			Parameters:  json.RawMessage(t.Parameters),
 		})
 	}
+	if len(req.Tools) > 0 {
+		oaiReq.ToolChoice = openAIToolChoice(req.ToolChoice, false)
+	}
+	oaiReq.Text = openAITextFormat(req.ResponseSchema)
 	return oaiReq
 }
 
@@ -594,7 +637,7 @@ func (p *copilotProvider) parseResponsesOutput(resp openAIResponse) Response {
 		switch out.Type {
 		case "message":
 			for _, c := range out.Content {
-				if c.Type == "text" {
+				if c.Type == "text" || c.Type == "output_text" {
 					result.Content += c.Text
 				}
 			}
@@ -627,6 +670,14 @@ func (p *copilotProvider) setHeaders(req *http.Request) {
 	req.Header.Set("x-initiator", "user")
 }
 
+// setVisionHeader marks requests carrying images; Copilot rejects image
+// content without it.
+func setVisionHeader(httpReq *http.Request, req Request) {
+	if hasImages(req.Messages) {
+		httpReq.Header.Set("Copilot-Vision-Request", "true")
+	}
+}
+
 // normalizeCopilotError wraps NormalizeHTTPError with Copilot-specific
 // guidance for 401 and 403 responses.
 func normalizeCopilotError(statusCode int, body string) *ProviderError {
@@ -646,3 +697,83 @@ func normalizeCopilotError(statusCode int, body string) *ProviderError {
 
 	return pe
 }
+
+// =========================================================================
+// Model listing  (/models)
+// =========================================================================
+
+type copilotModel struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Vendor       string `json:"vendor"`
+	Capabilities struct {
+		Type     string          `json:"type"`
+		Supports map[string]bool `json:"supports"`
+	} `json:"capabilities"`
+	SupportedEndpoints []string `json:"supported_endpoints"`
+}
+
+// ListModels implements ModelLister via GET /models.  Only chat models are
+// returned; embedding models cannot be used for prompts.
+func (p *copilotProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	p.setHeaders(httpReq)
+
+	var resp struct {
+		Data []copilotModel `json:"data"`
+	}
+	if err := fetchJSON(&p.client, httpReq, normalizeCopilotError, &resp); err != nil {
+		return nil, err
+	}
+	var models []ModelInfo
+	for _, m := range resp.Data {
+		if m.Capabilities.Type != "" && m.Capabilities.Type != "chat" {
+			continue
+		}
+		var caps []string
+		for name, ok := range m.Capabilities.Supports {
+			if ok {
+				caps = append(caps, name)
+			}
+		}
+		sort.Strings(caps)
+		info := ModelInfo{
+			ID:           m.ID,
+			Name:         m.Name,
+			Vendor:       m.Vendor,
+			Endpoints:    copilotEndpoints(m),
+			Capabilities: caps,
+		}
+		if m.Capabilities.Supports != nil {
+			vision := m.Capabilities.Supports["vision"]
+			info.Vision = &vision
+		}
+		models = append(models, info)
+	}
+	return sortModels(models), nil
+}
+
+// copilotEndpoints reports which APIs serve a model.  Older /models
+// responses lack supported_endpoints; the routing rule used by Stream is
+// the fallback.
+func copilotEndpoints(m copilotModel) []string {
+	var endpoints []string
+	for _, e := range m.SupportedEndpoints {
+		switch strings.TrimRight(e, "/") {
+		case "/chat/completions":
+			endpoints = append(endpoints, "chat")
+		case "/responses":
+			endpoints = append(endpoints, "responses")
+		}
+	}
+	if len(endpoints) > 0 {
+		return endpoints
+	}
+	if shouldUseResponsesAPI(m.ID) {
+		return []string{"responses"}
+	}
+	return []string{"chat"}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -29,9 +30,15 @@ type geminiContent struct {
 
 type geminiPart struct {
 	Text         string              `json:"text,omitempty"`
+	InlineData   *geminiInlineData   `json:"inlineData,omitempty"`
 	FunctionCall *geminiFunctionCall `json:"functionCall,omitempty"`
 }
 
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // base64
+}
+
 type geminiFunctionCall struct {
 	Name string          `json:"name"`
 	Args json.RawMessage `json:"args"`
@@ -48,15 +55,29 @@ type geminiFuncDecl struct {
 }
 
 type geminiRequest struct {
-	Contents          []geminiContent  `json:"contents"`
-	SystemInstruction *geminiContent   `json:"systemInstruction,omitempty"`
-	Tools             []geminiToolDecl `json:"tools,omitempty"`
-	GenerationConfig  *geminiGenConfig `json:"generationConfig,omitempty"`
+	Contents          []geminiContent   `json:"contents"`
+	SystemInstruction *geminiContent    `json:"systemInstruction,omitempty"`
+	Tools             []geminiToolDecl  `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig `json:"toolConfig,omitempty"`
+	GenerationConfig  *geminiGenConfig  `json:"generationConfig,omitempty"`
 }
 
 type geminiGenConfig struct {
 	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
 	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+
+	ResponseMimeType   string          `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"` // AUTO, ANY or NONE
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
 }
 
 type geminiCandidate struct {
@@ -204,6 +225,23 @@ func (p *googleProvider) readStream(ctx context.Context, body io.Reader, ch chan
 
 // --- Helpers ---
 
+// geminiParts converts a message's content to text and inlineData parts.
+func geminiParts(m Message) []geminiPart {
+	if !m.HasImages() {
+		return []geminiPart{{Text: m.Content}}
+	}
+	var parts []geminiPart
+	for _, part := range m.Parts {
+		switch part.Type {
+		case PartText:
+			parts = append(parts, geminiPart{Text: part.Text})
+		case PartImage:
+			parts = append(parts, geminiPart{InlineData: &geminiInlineData{MimeType: part.MIMEType, Data: part.Data}})
+		}
+	}
+	return parts
+}
+
 func (p *googleProvider) buildRequest(req Request) geminiRequest {
 	var system *geminiContent
 	var contents []geminiContent
@@ -221,7 +259,7 @@ func (p *googleProvider) buildRequest(req Request) geminiRequest {
 		}
 		contents = append(contents, geminiContent{
 			Role:  role,
-			Parts: []geminiPart{{Text: m.Content}},
+			Parts: geminiParts(m),
 		})
 	}
 
@@ -230,10 +268,15 @@ func (p *googleProvider) buildRequest(req Request) geminiRequest {
 		SystemInstruction: system,
 	}
 
-	if req.MaxTokens > 0 || req.Temperature != nil {
+	if req.MaxTokens > 0 || req.Temperature != nil || req.TopP != nil || len(req.ResponseSchema) > 0 {
 		gemReq.GenerationConfig = &geminiGenConfig{
 			MaxOutputTokens: req.MaxTokens,
 			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+		}
+		if len(req.ResponseSchema) > 0 {
+			gemReq.GenerationConfig.ResponseMimeType = "application/json"
+			gemReq.GenerationConfig.ResponseJSONSchema = req.ResponseSchema
 		}
 	}
 
@@ -247,11 +290,32 @@ func (p *googleProvider) buildRequest(req Request) geminiRequest {
 			})
 		}
 		gemReq.Tools = []geminiToolDecl{{FunctionDeclarations: decls}}
+		gemReq.ToolConfig = geminiToolChoice(req.ToolChoice)
 	}
 
 	return gemReq
 }
 
+// geminiToolChoice maps a tool choice onto functionCallingConfig; "required"
+// becomes ANY and a tool name becomes ANY restricted to that function.
+func geminiToolChoice(choice string) *geminiToolConfig {
+	var cfg geminiFunctionCallingConfig
+	switch choice {
+	case "":
+		return nil
+	case ToolChoiceAuto:
+		cfg.Mode = "AUTO"
+	case ToolChoiceNone:
+		cfg.Mode = "NONE"
+	case ToolChoiceRequired:
+		cfg.Mode = "ANY"
+	default:
+		cfg.Mode = "ANY"
+		cfg.AllowedFunctionNames = []string{choice}
+	}
+	return &geminiToolConfig{FunctionCallingConfig: cfg}
+}
+
 func (p *googleProvider) buildURL(stream bool) string {
 	base := strings.TrimRight(p.endpoint, "/")
 	model := p.model
@@ -279,3 +343,62 @@ func (p *googleProvider) parseResponse(resp geminiResponse) Response {
 	}
 	return result
 }
+
+// --- Model listing ---
+
+// ListModels implements ModelLister via models.list, following pages.
+func (p *googleProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	var models []ModelInfo
+	pageToken := ""
+	for {
+		query := url.Values{"key": {p.apiKey}, "pageSize": {"1000"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		endpoint := strings.TrimRight(p.endpoint, "/") + "/v1beta/models?" + query.Encode()
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp struct {
+			Models []struct {
+				Name                       string   `json:"name"`
+				DisplayName                string   `json:"displayName"`
+				SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := fetchJSON(&p.client, httpReq, normalizeFor("google"), &resp); err != nil {
+			return nil, err
+		}
+		for _, m := range resp.Models {
+			if !generatesContent(m.SupportedGenerationMethods) {
+				continue
+			}
+			id := strings.TrimPrefix(m.Name, "models/")
+			models = append(models, ModelInfo{
+				ID:           id,
+				Name:         m.DisplayName,
+				Vendor:       "google",
+				Capabilities: m.SupportedGenerationMethods,
+				Vision:       KnownVision("google", id),
+			})
+		}
+		if resp.NextPageToken == "" || resp.NextPageToken == pageToken {
+			return sortModels(models), nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// generatesContent reports whether a Gemini model supports generateContent,
+// which rules out embedding, AQA and other models that cannot chat.
+func generatesContent(methods []string) bool {
+	for _, m := range methods {
+		if m == "generateContent" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModelInfo describes one model available to the configured credentials.
+type ModelInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"` // display name, when the API has one
+
+	// Vendor is the model owner (OpenAI owned_by, Copilot vendor).
+	Vendor string `json:"vendor,omitempty"`
+
+	// Endpoints lists the Copilot APIs that serve the model: "chat"
+	// (/chat/completions) and/or "responses" (/responses).
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Capabilities holds provider-reported features, e.g. Copilot's
+	// "vision" or "tool_calls", or Gemini's generation methods.
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// Vision reports whether the model accepts image input, or is nil when
+	// the provider does not say.
+	Vision *bool `json:"vision,omitempty"`
+}
+
+// ModelLister is implemented by providers that can enumerate their models.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// listingModel stands in for the model when resolving a provider only to
+// list models, since the user may not have picked one yet.
+const listingModel = "-"
+
+// ResolveModelLister resolves the configured provider like Resolve, without
+// requiring a model, and returns it as a ModelLister.
+func ResolveModelLister(cfg map[string]string) (ModelLister, error) {
+	if strings.TrimSpace(cfg["model"]) == "" {
+		withModel := make(map[string]string, len(cfg)+1)
+		for k, v := range cfg {
+			withModel[k] = v
+		}
+		withModel["model"] = listingModel
+		cfg = withModel
+	}
+	p, err := Resolve(cfg)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := p.(ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support listing models", p.Name())
+	}
+	return lister, nil
+}
+
+// visionPrefixes records which model families accept image input, for the
+// providers whose model lists do not say.  The longest matching prefix wins;
+// a model matching none is unknown.
+var visionPrefixes = map[string]map[string]bool{
+	"openai": {
+		"gpt-4o": true, "chatgpt-4o": true, "gpt-4-turbo": true, "gpt-4.1": true, "gpt-4.5": true,
+		"gpt-5": true, "o1": true, "o3": true, "o4-mini": true,
+		"gpt-3.5": false, "gpt-4-0": false, "o1-mini": false, "o1-preview": false, "o3-mini": false,
+		"text-embedding": false, "davinci": false, "babbage": false,
+	},
+	"anthropic": {
+		"claude-3-opus": true, "claude-3-sonnet": true, "claude-3-haiku": true,
+		"claude-3-5-sonnet": true, "claude-3-7-sonnet": true,
+		"claude-opus-4": true, "claude-sonnet-4": true, "claude-haiku-4": true,
+		"claude-2": false, "claude-instant": false,
+	},
+	"google": {
+		"gemini-": true, "gemini-pro-vision": true,
+		"gemini-pro": false, "gemini-1.0-pro": false, "gemini-1.0-pro-vision": true,
+		"embedding": false, "text-embedding": false, "aqa": false,
+	},
+}
+
+// KnownVision reports whether a model accepts image input according to the
+// built-in table for its provider, or nil when the table does not know it.
+func KnownVision(providerName, model string) *bool {
+	var (
+		match  string
+		vision bool
+	)
+	for prefix, v := range visionPrefixes[providerName] {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(match) {
+			match, vision = prefix, v
+		}
+	}
+	if match == "" {
+		return nil
+	}
+	return &vision
+}
+
+// fetchJSON sends a GET request and decodes the JSON response into out.
+// Non-200 responses are converted with normalize.
+func fetchJSON(client *http.Client, req *http.Request, normalize func(int, string) *ProviderError, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return normalize(resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("unmarshal models: %w", err)
+	}
+	return nil
+}
+
+// sortModels orders models by ID.
+func sortModels(models []ModelInfo) []ModelInfo {
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models
+}
+
+// --- Local cache ---
+
+// ModelCacheTTL is how long a cached model list is used before refetching.
+const ModelCacheTTL = 24 * time.Hour
+
+// ModelCache is a model list saved under .rai/cache/.  Lists are kept per
+// credential as well as per endpoint, since different keys can see
+// different models.
+type ModelCache struct {
+	Provider string      `json:"provider"`
+	Endpoint string      `json:"endpoint"`
+	Account  string      `json:"account,omitempty"` // CredentialHash of the key used
+	Fetched  time.Time   `json:"fetched"`
+	Models   []ModelInfo `json:"models"`
+}
+
+// CredentialHash returns a short hash identifying the API key or Copilot
+// token in cfg, or "" when none is set.  The key itself is never stored.
+func CredentialHash(cfg map[string]string) string {
+	for _, key := range []string{"api-key", "api_key", "copilot-token"} {
+		if v := cfg[key]; v != "" {
+			sum := sha256.Sum256([]byte(v))
+			return hex.EncodeToString(sum[:8])
+		}
+	}
+	return ""
+}
+
+// modelCachePath returns the cache file for a provider, endpoint and
+// credential.  They are hashed so endpoints never need escaping in file names.
+func modelCachePath(baseDir, providerName, endpoint, account string) string {
+	sum := sha256.Sum256([]byte(providerName + "\n" + endpoint + "\n" + account))
+	name := fmt.Sprintf("models-%s.json", hex.EncodeToString(sum[:6]))
+	return filepath.Join(baseDir, ".rai", "cache", name)
+}
+
+// LoadModelCache returns the cached model list for a provider, endpoint and
+// credential hash, and false when there is none or it is older than
+// ModelCacheTTL.
+func LoadModelCache(baseDir, providerName, endpoint, account string, now time.Time) (ModelCache, bool) {
+	data, err := os.ReadFile(modelCachePath(baseDir, providerName, endpoint, account))
+	if err != nil {
+		return ModelCache{}, false
+	}
+	var cache ModelCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return ModelCache{}, false
+	}
+	if cache.Provider != providerName || cache.Endpoint != endpoint || cache.Account != account || now.Sub(cache.Fetched) > ModelCacheTTL {
+		return ModelCache{}, false
+	}
+	return cache, true
+}
+
+// SaveModelCache writes a model list to the cache.
+func SaveModelCache(baseDir string, cache ModelCache) error {
+	path := modelCachePath(baseDir, cache.Provider, cache.Endpoint, cache.Account)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// normalizeFor returns NormalizeHTTPError bound to a provider name.
+func normalizeFor(providerName string) func(int, string) *ProviderError {
+	return func(statusCode int, body string) *ProviderError {
+		return NormalizeHTTPError(providerName, statusCode, body)
+	}
+}
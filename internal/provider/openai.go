@@ -26,9 +26,15 @@ func (p *openAIProvider) Name() string { return "openai" }
 // --- Request/Response types ---
 
 type openAIInput struct {
-	Role       string `json:"role"`
-	Content    string `json:"content"`
-	ToolCallID string `json:"tool_call_id,omitempty"`
+	Role       string      `json:"role"`
+	Content    interface{} `json:"content"` // string, or []openAIInputPart for images
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+}
+
+type openAIInputPart struct {
+	Type     string `json:"type"` // "input_text" or "input_image"
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
 }
 
 type openAITool struct {
@@ -39,14 +45,17 @@ type openAITool struct {
 }
 
 type openAIRequest struct {
-	Model       string           `json:"model"`
-	Input       []openAIInput    `json:"input"`
-	Stream      bool             `json:"stream,omitempty"`
-	MaxTokens   int              `json:"max_output_tokens,omitempty"`
-	Temperature *float64         `json:"temperature,omitempty"`
-	Reasoning   *openAIReasoning `json:"reasoning,omitempty"`
-	Include     []string         `json:"include,omitempty"`
-	Tools       []openAITool     `json:"tools,omitempty"`
+	Model       string            `json:"model"`
+	Input       []openAIInput     `json:"input"`
+	Stream      bool              `json:"stream,omitempty"`
+	MaxTokens   int               `json:"max_output_tokens,omitempty"`
+	Temperature *float64          `json:"temperature,omitempty"`
+	TopP        *float64          `json:"top_p,omitempty"`
+	Reasoning   *openAIReasoning  `json:"reasoning,omitempty"`
+	Include     []string          `json:"include,omitempty"`
+	Tools       []openAITool      `json:"tools,omitempty"`
+	ToolChoice  json.RawMessage   `json:"tool_choice,omitempty"`
+	Text        *openAITextConfig `json:"text,omitempty"`
 }
 
 type openAIReasoning struct {
@@ -248,17 +257,13 @@ func (p *openAIProvider) readSSE(ctx context.Context, body io.Reader, ch chan<-
 // --- Helpers ---
 
 func (p *openAIProvider) buildRequest(req Request, stream bool) openAIRequest {
-	var input []openAIInput
-	for _, m := range req.Messages {
-		input = append(input, openAIInput{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID})
-	}
-
 	oaiReq := openAIRequest{
 		Model:       p.model,
-		Input:       input,
+		Input:       openAIInputs(req.Messages),
 		Stream:      stream,
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
+		TopP:        req.TopP,
 	}
 	if p.reasoningSummary != "" {
 		oaiReq.Reasoning = &openAIReasoning{Summary: p.reasoningSummary}
@@ -279,10 +284,37 @@ func (p *openAIProvider) buildRequest(req Request, stream bool) openAIRequest {
 			Parameters:  json.RawMessage(t.Parameters),
 		})
 	}
+	if len(req.Tools) > 0 {
+		oaiReq.ToolChoice = openAIToolChoice(req.ToolChoice, false)
+	}
+	oaiReq.Text = openAITextFormat(req.ResponseSchema)
 
 	return oaiReq
 }
 
+// openAIInputs converts messages to Responses API input items.  Messages
+// with images use input_text/input_image parts; others keep plain content.
+func openAIInputs(messages []Message) []openAIInput {
+	var input []openAIInput
+	for _, m := range messages {
+		in := openAIInput{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		if m.HasImages() {
+			var parts []openAIInputPart
+			for _, part := range m.Parts {
+				switch part.Type {
+				case PartText:
+					parts = append(parts, openAIInputPart{Type: "input_text", Text: part.Text})
+				case PartImage:
+					parts = append(parts, openAIInputPart{Type: "input_image", ImageURL: part.DataURL()})
+				}
+			}
+			in.Content = parts
+		}
+		input = append(input, in)
+	}
+	return input
+}
+
 func (p *openAIProvider) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.apiKey)
@@ -294,7 +326,8 @@ func (p *openAIProvider) parseResponse(resp openAIResponse) Response {
 		switch out.Type {
 		case "message":
 			for _, c := range out.Content {
-				if c.Type == "text" {
+				// The Responses API labels message text "output_text".
+				if c.Type == "text" || c.Type == "output_text" {
 					result.Content += c.Text
 				}
 			}
@@ -314,3 +347,30 @@ func (p *openAIProvider) parseResponse(resp openAIResponse) Response {
 	}
 	return result
 }
+
+// --- Model listing ---
+
+// ListModels implements ModelLister via GET /models.
+func (p *openAIProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	url := strings.TrimRight(p.endpoint, "/") + "/models"
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.setHeaders(httpReq)
+
+	var resp struct {
+		Data []struct {
+			ID      string `json:"id"`
+			OwnedBy string `json:"owned_by"`
+		} `json:"data"`
+	}
+	if err := fetchJSON(&p.client, httpReq, normalizeFor("openai"), &resp); err != nil {
+		return nil, err
+	}
+	models := make([]ModelInfo, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		models = append(models, ModelInfo{ID: m.ID, Vendor: m.OwnedBy, Vision: KnownVision("openai", m.ID)})
+	}
+	return sortModels(models), nil
+}
@@ -0,0 +1,205 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"run-ai/internal/config"
+)
+
+// Tool choice modes shared by every provider.  Any other ToolChoice value
+// names a specific tool the model must call.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+var toolNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// GenerationOptions are the sampling settings accepted in config, agent
+// frontmatter and CLI flags, validated once and applied to every Request.
+type GenerationOptions struct {
+	MaxTokens   int      // 0 means provider default
+	Temperature *float64 // nil means provider default
+	TopP        *float64 // nil means provider default
+	ToolChoice  string   // "", auto, none, required, or a tool name
+}
+
+// ParseGenerationOptions reads max-tokens (or max-output-tokens),
+// temperature, top-p and tool-choice from merged config.  Both hyphen and
+// underscore spellings are accepted.  Invalid values are rejected rather than
+// silently ignored.
+func ParseGenerationOptions(cfg map[string]string) (GenerationOptions, error) {
+	var opts GenerationOptions
+
+	rawMax := strings.TrimSpace(config.Lookup(cfg, "max-tokens"))
+	maxKey := "max-tokens"
+	if rawMax == "" {
+		rawMax = strings.TrimSpace(config.Lookup(cfg, "max-output-tokens"))
+		maxKey = "max-output-tokens"
+	}
+	if rawMax != "" {
+		n, err := strconv.Atoi(rawMax)
+		if err != nil || n <= 0 {
+			return GenerationOptions{}, fmt.Errorf("%s must be a positive integer, got %q", maxKey, rawMax)
+		}
+		opts.MaxTokens = n
+	}
+
+	temp, err := parseFloatOption(cfg, "temperature", 0, 2, true)
+	if err != nil {
+		return GenerationOptions{}, err
+	}
+	opts.Temperature = temp
+
+	topP, err := parseFloatOption(cfg, "top-p", 0, 1, false)
+	if err != nil {
+		return GenerationOptions{}, err
+	}
+	opts.TopP = topP
+
+	choice := strings.TrimSpace(config.Lookup(cfg, "tool-choice"))
+	switch strings.ToLower(choice) {
+	case "":
+	case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+		opts.ToolChoice = strings.ToLower(choice)
+	default:
+		if !toolNameRe.MatchString(choice) {
+			return GenerationOptions{}, fmt.Errorf("tool-choice must be auto, none, required, or a tool name, got %q", choice)
+		}
+		opts.ToolChoice = choice
+	}
+
+	return opts, nil
+}
+
+// parseFloatOption parses key as a float in [min, max].  When minInclusive is
+// false the lower bound is exclusive.
+func parseFloatOption(cfg map[string]string, key string, min, max float64, minInclusive bool) (*float64, error) {
+	raw := strings.TrimSpace(config.Lookup(cfg, key))
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number, got %q", key, raw)
+	}
+	if v > max || v < min || (!minInclusive && v == min) {
+		lower := "["
+		if !minInclusive {
+			lower = "("
+		}
+		return nil, fmt.Errorf("%s must be in %s%g, %g], got %g", key, lower, min, max, v)
+	}
+	return &v, nil
+}
+
+// IsForced reports whether the tool choice requires the model to call a tool.
+func (o GenerationOptions) IsForced() bool {
+	return o.ToolChoice != "" && o.ToolChoice != ToolChoiceAuto && o.ToolChoice != ToolChoiceNone
+}
+
+// Apply copies the options into req.
+func (o GenerationOptions) Apply(req *Request) {
+	req.MaxTokens = o.MaxTokens
+	req.Temperature = o.Temperature
+	req.TopP = o.TopP
+	req.ToolChoice = o.ToolChoice
+}
+
+// --- Per-provider tool_choice encodings ---
+
+// openAIToolChoice encodes a tool choice for the Responses and Chat
+// Completions APIs.  chat selects the Chat Completions shape for named tools.
+func openAIToolChoice(choice string, chat bool) json.RawMessage {
+	switch choice {
+	case "":
+		return nil
+	case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+		data, _ := json.Marshal(choice)
+		return data
+	}
+	if chat {
+		data, _ := json.Marshal(map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": choice},
+		})
+		return data
+	}
+	data, _ := json.Marshal(map[string]string{"type": "function", "name": choice})
+	return data
+}
+
+// anthropicToolChoice encodes a tool choice for the Messages API, where
+// "required" is spelled "any".
+func anthropicToolChoice(choice string) json.RawMessage {
+	var v map[string]string
+	switch choice {
+	case "":
+		return nil
+	case ToolChoiceAuto, ToolChoiceNone:
+		v = map[string]string{"type": choice}
+	case ToolChoiceRequired:
+		v = map[string]string{"type": "any"}
+	default:
+		v = map[string]string{"type": "tool", "name": choice}
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// --- Structured output encodings ---
+
+// responseSchemaName names the schema in APIs that require one.
+const responseSchemaName = "response"
+
+type openAITextConfig struct {
+	Format openAITextFormatSpec `json:"format"`
+}
+
+type openAITextFormatSpec struct {
+	Type   string          `json:"type"`
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	Strict bool            `json:"strict"`
+}
+
+// openAITextFormat encodes a response schema for the Responses API.  Strict
+// mode is off because it rejects common schemas (optional properties,
+// missing additionalProperties); the runner validates the answer instead.
+func openAITextFormat(schema json.RawMessage) *openAITextConfig {
+	if len(schema) == 0 {
+		return nil
+	}
+	return &openAITextConfig{Format: openAITextFormatSpec{
+		Type:   "json_schema",
+		Name:   responseSchemaName,
+		Schema: schema,
+	}}
+}
+
+// chatResponseFormat encodes a response schema for Chat Completions.
+func chatResponseFormat(schema json.RawMessage) json.RawMessage {
+	if len(schema) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"type": "json_schema",
+		"json_schema": map[string]interface{}{
+			"name":   responseSchemaName,
+			"schema": schema,
+			"strict": false,
+		},
+	})
+	return data
+}
+
+// schemaInstruction asks for schema-conforming JSON in prompt text, for
+// providers without a native structured-output mode.
+func schemaInstruction(schema json.RawMessage) string {
+	return "Respond with only a JSON value, without code fences or commentary, that conforms to this JSON Schema:\n" + string(schema)
+}
@@ -11,6 +11,7 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -20,17 +21,61 @@ import (
 
 // Message represents a single message in a conversation.
 type Message struct {
-	Role       string // "system", "user", "assistant", "tool"
-	Content    string
-	ToolCalls  []ToolCall // For assistant tool call messages (chat APIs).
-	ToolCallID string     // For tool result messages (chat APIs).
+	Role       string     `json:"role"` // "system", "user", "assistant", "tool"
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // For assistant tool call messages (chat APIs).
+	ToolCallID string     `json:"tool_call_id,omitempty"` // For tool result messages (chat APIs).
+
+	// Parts, when set, is the content as typed parts and takes precedence
+	// over Content, which then holds only the text for logs and sessions.
+	// User messages use it to carry images alongside the prompt.
+	Parts []ContentPart `json:"parts,omitempty"`
+}
+
+// Content part types.
+const (
+	PartText  = "text"
+	PartImage = "image"
+)
+
+// ContentPart is one piece of a multimodal message.
+type ContentPart struct {
+	Type     string `json:"type"`                // PartText or PartImage
+	Text     string `json:"text,omitempty"`      // PartText
+	MIMEType string `json:"mime_type,omitempty"` // PartImage, e.g. "image/png"
+	Data     string `json:"data,omitempty"`      // PartImage, base64-encoded
+}
+
+// DataURL returns an image part as a data: URL.
+func (p ContentPart) DataURL() string {
+	return "data:" + p.MIMEType + ";base64," + p.Data
+}
+
+// HasImages reports whether the message carries image parts.
+func (m Message) HasImages() bool {
+	for _, p := range m.Parts {
+		if p.Type == PartImage {
+			return true
+		}
+	}
+	return false
+}
+
+// hasImages reports whether any message carries image parts.
+func hasImages(messages []Message) bool {
+	for _, m := range messages {
+		if m.HasImages() {
+			return true
+		}
+	}
+	return false
 }
 
 // ToolCall represents a tool invocation requested by the provider.
 type ToolCall struct {
-	ID        string
-	Name      string
-	Arguments string // JSON-encoded arguments
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON-encoded arguments
 }
 
 // ToolDef describes a tool available to the provider.
@@ -57,6 +102,13 @@ type Request struct {
 	Model       string
 	MaxTokens   int
 	Temperature *float64 // nil means provider default
+	TopP        *float64 // nil means provider default
+	ToolChoice  string   // see GenerationOptions; ignored when Tools is empty
+
+	// ResponseSchema, when set, is a JSON Schema the final answer must
+	// satisfy.  Providers with a native structured-output mode pass it on;
+	// the others add it to the system prompt as an instruction.
+	ResponseSchema json.RawMessage
 }
 
 // Response is the complete, non-streaming result of a provider call.
@@ -185,6 +237,19 @@ func newGoogleProvider(endpoint, apiKey, model string, cfg map[string]string) (P
 // newCopilotProvider creates a GitHub Copilot provider.
 // Token is sourced from api-key/api_key/copilot-token in the config map.
 // The CLI layer is responsible for loading stored tokens into the config.
+// defaultCopilotModel is the free Copilot model used when none is configured.
+const defaultCopilotModel = "gpt-5-mini"
+
+// DefaultModel returns the model the provider selected by cfg falls back to
+// when cfg sets none, or "" if that provider requires an explicit model.
+func DefaultModel(cfg map[string]string) string {
+	switch strings.TrimSpace(cfg["provider"]) {
+	case "github-copilot", "github-copilot-enterprise":
+		return defaultCopilotModel
+	}
+	return ""
+}
+
 func newCopilotProvider(cfg map[string]string, providerID string) (Provider, error) {
 	token := cfg["api-key"]
 	if token == "" {
@@ -207,7 +272,7 @@ func newCopilotProvider(cfg map[string]string, providerID string) (Provider, err
 
 	model := cfg["model"]
 	if model == "" {
-		model = "gpt-5-mini" // default free Copilot model
+		model = DefaultModel(cfg)
 	}
 	reasoningSummary := reasoningSummarySetting(cfg)
 
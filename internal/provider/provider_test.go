@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // --- Resolve tests ---
@@ -131,6 +133,12 @@ func TestResolveGitHubCopilotDefaultModel(t *testing.T) {
 	if cp.model != "gpt-5-mini" {
 		t.Fatalf("default model = %q, want gpt-5-mini", cp.model)
 	}
+	if got := DefaultModel(map[string]string{"provider": "github-copilot"}); got != cp.model {
+		t.Fatalf("DefaultModel = %q, want %q", got, cp.model)
+	}
+	if got := DefaultModel(map[string]string{"endpoint": "https://api.openai.com/v1"}); got != "" {
+		t.Fatalf("DefaultModel(openai) = %q, want empty", got)
+	}
 }
 
 func TestResolveAPIKeyUnderscore(t *testing.T) {
@@ -1112,3 +1120,406 @@ func TestCopilotHTTPError(t *testing.T) {
 		t.Fatalf("status = %d", pe.StatusCode)
 	}
 }
+
+// --- Generation options tests ---
+
+func TestParseGenerationOptions(t *testing.T) {
+	opts, err := ParseGenerationOptions(map[string]string{
+		"temperature": "0.3",
+		"max_tokens":  "256",
+		"top-p":       "0.9",
+		"tool-choice": "Required",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.MaxTokens != 256 || *opts.Temperature != 0.3 || *opts.TopP != 0.9 || opts.ToolChoice != "required" {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	opts, err = ParseGenerationOptions(map[string]string{"max-output-tokens": "10", "tool_choice": "read-file"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.MaxTokens != 10 || opts.ToolChoice != "read-file" || !opts.IsForced() {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+}
+
+func TestParseGenerationOptionsInvalid(t *testing.T) {
+	cases := map[string]map[string]string{
+		"temperature must be a number":         {"temperature": "hot"},
+		"temperature must be in [0, 2]":        {"temperature": "3"},
+		"top-p must be in (0, 1]":              {"top-p": "0"},
+		"max-tokens must be a positive":        {"max-tokens": "-5"},
+		"max-output-tokens must be a positive": {"max-output-tokens": "lots"},
+		"tool-choice must be":                  {"tool-choice": "run everything"},
+	}
+	for want, cfg := range cases {
+		_, err := ParseGenerationOptions(cfg)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseGenerationOptions(%v) = %v, want error containing %q", cfg, err, want)
+		}
+	}
+}
+
+func TestGenerationOptionsMapping(t *testing.T) {
+	temp, topP := 0.5, 0.8
+	req := Request{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+		Tools:    []ToolDef{{Name: "terminal", Parameters: `{"type":"object"}`}},
+	}
+	GenerationOptions{MaxTokens: 100, Temperature: &temp, TopP: &topP, ToolChoice: "terminal"}.Apply(&req)
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return string(data)
+	}
+
+	oai := encode((&openAIProvider{model: "m"}).buildRequest(req, false))
+	for _, want := range []string{`"max_output_tokens":100`, `"temperature":0.5`, `"top_p":0.8`, `"tool_choice":{"name":"terminal","type":"function"}`} {
+		if !strings.Contains(oai, want) {
+			t.Errorf("openai request missing %s: %s", want, oai)
+		}
+	}
+
+	ant := encode((&anthropicProvider{model: "m"}).buildRequest(req, false))
+	for _, want := range []string{`"max_tokens":100`, `"top_p":0.8`, `"tool_choice":{"name":"terminal","type":"tool"}`} {
+		if !strings.Contains(ant, want) {
+			t.Errorf("anthropic request missing %s: %s", want, ant)
+		}
+	}
+
+	gem := encode((&googleProvider{model: "m"}).buildRequest(req))
+	for _, want := range []string{`"maxOutputTokens":100`, `"topP":0.8`, `"functionCallingConfig":{"mode":"ANY","allowedFunctionNames":["terminal"]}`} {
+		if !strings.Contains(gem, want) {
+			t.Errorf("gemini request missing %s: %s", want, gem)
+		}
+	}
+
+	cp := &copilotProvider{model: "gpt-4o"}
+	chat := encode(cp.buildChatRequest(req, false))
+	for _, want := range []string{`"max_tokens":100`, `"top_p":0.8`, `"tool_choice":{"function":{"name":"terminal"},"type":"function"}`} {
+		if !strings.Contains(chat, want) {
+			t.Errorf("copilot chat request missing %s: %s", want, chat)
+		}
+	}
+	resp := encode(cp.buildResponsesRequest(req, false))
+	if !strings.Contains(resp, `"top_p":0.8`) || !strings.Contains(resp, `"tool_choice":{"name":"terminal","type":"function"}`) {
+		t.Errorf("copilot responses request missing options: %s", resp)
+	}
+
+	req.ToolChoice = ToolChoiceRequired
+	if got := encode((&anthropicProvider{model: "m"}).buildRequest(req, false)); !strings.Contains(got, `"tool_choice":{"type":"any"}`) {
+		t.Errorf("anthropic required should map to any: %s", got)
+	}
+
+	req.Tools = nil
+	if got := encode((&openAIProvider{model: "m"}).buildRequest(req, false)); strings.Contains(got, "tool_choice") {
+		t.Errorf("tool_choice sent without tools: %s", got)
+	}
+}
+
+func TestResponseSchemaMapping(t *testing.T) {
+	req := Request{
+		Messages:       []Message{{Role: "system", Content: "Be terse."}, {Role: "user", Content: "hi"}},
+		ResponseSchema: json.RawMessage(`{"type":"object"}`),
+	}
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return string(data)
+	}
+
+	oai := encode((&openAIProvider{model: "m"}).buildRequest(req, false))
+	if !strings.Contains(oai, `"text":{"format":{"type":"json_schema","name":"response","schema":{"type":"object"},"strict":false}}`) {
+		t.Errorf("openai request missing text format: %s", oai)
+	}
+
+	cp := &copilotProvider{model: "gpt-4o"}
+	chat := encode(cp.buildChatRequest(req, false))
+	if !strings.Contains(chat, `"response_format":{"json_schema":{"name":"response","schema":{"type":"object"},"strict":false},"type":"json_schema"}`) {
+		t.Errorf("copilot chat request missing response_format: %s", chat)
+	}
+	if resp := encode(cp.buildResponsesRequest(req, false)); !strings.Contains(resp, `"format":{"type":"json_schema"`) {
+		t.Errorf("copilot responses request missing text format: %s", resp)
+	}
+
+	gem := encode((&googleProvider{model: "m"}).buildRequest(req))
+	if !strings.Contains(gem, `"responseMimeType":"application/json","responseJsonSchema":{"type":"object"}`) {
+		t.Errorf("gemini request missing response schema: %s", gem)
+	}
+
+	ant := (&anthropicProvider{model: "m"}).buildRequest(req, false)
+	if !strings.HasPrefix(ant.System, "Be terse.\n\n") || !strings.Contains(ant.System, `{"type":"object"}`) {
+		t.Errorf("anthropic system prompt missing schema instruction: %q", ant.System)
+	}
+
+	req.ResponseSchema = nil
+	if got := encode((&openAIProvider{model: "m"}).buildRequest(req, false)); strings.Contains(got, `"text"`) {
+		t.Errorf("text format sent without schema: %s", got)
+	}
+}
+
+func TestImagePartsMapping(t *testing.T) {
+	req := Request{Messages: []Message{
+		{Role: "system", Content: "Be terse."},
+		{Role: "user", Content: "what is this?", Parts: []ContentPart{
+			{Type: PartText, Text: "what is this?"},
+			{Type: PartImage, MIMEType: "image/png", Data: "iVBORw0KGgo="},
+		}},
+	}}
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return string(data)
+	}
+
+	oai := encode((&openAIProvider{model: "m"}).buildRequest(req, false))
+	if !strings.Contains(oai, `"content":[{"type":"input_text","text":"what is this?"},{"type":"input_image","image_url":"data:image/png;base64,iVBORw0KGgo="}]`) {
+		t.Errorf("openai request missing input_image: %s", oai)
+	}
+	if !strings.Contains(oai, `{"role":"system","content":"Be terse."}`) {
+		t.Errorf("openai text-only message changed: %s", oai)
+	}
+
+	ant := encode((&anthropicProvider{model: "m"}).buildRequest(req, false))
+	if !strings.Contains(ant, `{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBORw0KGgo="}}`) {
+		t.Errorf("anthropic request missing image block: %s", ant)
+	}
+
+	gem := encode((&googleProvider{model: "m"}).buildRequest(req))
+	if !strings.Contains(gem, `"parts":[{"text":"what is this?"},{"inlineData":{"mimeType":"image/png","data":"iVBORw0KGgo="}}]`) {
+		t.Errorf("gemini request missing inlineData: %s", gem)
+	}
+
+	chat := encode((&copilotProvider{model: "gpt-4o"}).buildChatRequest(req, false))
+	if !strings.Contains(chat, `{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}}`) {
+		t.Errorf("copilot chat request missing image_url: %s", chat)
+	}
+}
+
+func TestCopilotVisionHeader(t *testing.T) {
+	var visionHeaders []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		visionHeaders = append(visionHeaders, r.Header.Get("Copilot-Vision-Request"))
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer srv.Close()
+
+	cp := &copilotProvider{baseURL: srv.URL, token: "tok", model: "gpt-4o"}
+	image := Message{Role: "user", Content: "see", Parts: []ContentPart{{Type: PartText, Text: "see"}, {Type: PartImage, MIMEType: "image/png", Data: "AA=="}}}
+	for _, msgs := range [][]Message{{image}, {{Role: "user", Content: "plain"}}} {
+		if _, err := cp.Complete(context.Background(), Request{Messages: msgs}); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+	}
+	if len(visionHeaders) != 2 || visionHeaders[0] != "true" || visionHeaders[1] != "" {
+		t.Fatalf("Copilot-Vision-Request headers = %q", visionHeaders)
+	}
+}
+
+// --- Model listing tests ---
+
+func modelIDs(models []ModelInfo) string {
+	ids := make([]string, len(models))
+	for i, m := range models {
+		ids[i] = m.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestOpenAIListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer sk" {
+			t.Errorf("unexpected request %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		fmt.Fprint(w, `{"data":[{"id":"gpt-4o","owned_by":"openai"},{"id":"gpt-4.1","owned_by":"system"}]}`)
+	}))
+	defer srv.Close()
+
+	lister, err := ResolveModelLister(map[string]string{"endpoint": srv.URL + "/v1", "api-key": "sk"})
+	if err != nil {
+		t.Fatalf("ResolveModelLister: %v", err)
+	}
+	models, err := lister.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if got := modelIDs(models); got != "gpt-4.1,gpt-4o" {
+		t.Fatalf("models = %s", got)
+	}
+	if models[1].Vendor != "openai" {
+		t.Errorf("vendor = %q", models[1].Vendor)
+	}
+	if models[1].Vision == nil || !*models[1].Vision {
+		t.Errorf("vision = %v", models[1].Vision)
+	}
+}
+
+func TestKnownVision(t *testing.T) {
+	for _, tc := range []struct {
+		provider, model string
+		want            string
+	}{
+		{"openai", "gpt-4o-mini", "true"},
+		{"openai", "o3-mini", "false"},
+		{"openai", "o3", "true"},
+		{"openai", "llama3", "unknown"},
+		{"anthropic", "claude-sonnet-4-20250514", "true"},
+		{"anthropic", "claude-2.1", "false"},
+		{"google", "gemini-2.5-flash", "true"},
+		{"google", "gemini-pro", "false"},
+		{"google", "gemini-pro-vision", "true"},
+		{"github-copilot", "gpt-4o", "unknown"},
+	} {
+		got := "unknown"
+		if v := KnownVision(tc.provider, tc.model); v != nil {
+			got = fmt.Sprint(*v)
+		}
+		if got != tc.want {
+			t.Errorf("KnownVision(%s, %s) = %s, want %s", tc.provider, tc.model, got, tc.want)
+		}
+	}
+}
+
+func TestAnthropicListModelsPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "k" {
+			t.Errorf("missing api key header")
+		}
+		if r.URL.Query().Get("after_id") == "" {
+			fmt.Fprint(w, `{"data":[{"id":"claude-b","display_name":"Claude B"}],"has_more":true,"last_id":"claude-b"}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":[{"id":"claude-a","display_name":"Claude A"}],"has_more":false,"last_id":"claude-a"}`)
+	}))
+	defer srv.Close()
+
+	p := &anthropicProvider{endpoint: srv.URL, apiKey: "k"}
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if got := modelIDs(models); got != "claude-a,claude-b" {
+		t.Fatalf("models = %s", got)
+	}
+	if models[0].Name != "Claude A" {
+		t.Errorf("name = %q", models[0].Name)
+	}
+}
+
+func TestGoogleListModelsPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models" || r.URL.Query().Get("key") != "gk" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"models":[{"name":"models/gemini-pro","displayName":"Gemini Pro","supportedGenerationMethods":["generateContent"]}],"nextPageToken":"p2"}`)
+			return
+		}
+		fmt.Fprint(w, `{"models":[{"name":"models/embedding-001","supportedGenerationMethods":["embedContent"]},{"name":"models/gemini-1.5-flash","supportedGenerationMethods":["generateContent","countTokens"]}]}`)
+	}))
+	defer srv.Close()
+
+	p := &googleProvider{endpoint: srv.URL, apiKey: "gk"}
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if got := modelIDs(models); got != "gemini-1.5-flash,gemini-pro" {
+		t.Fatalf("models = %s", got)
+	}
+	if strings.Join(models[1].Capabilities, ",") != "generateContent" {
+		t.Errorf("capabilities = %v", models[1].Capabilities)
+	}
+}
+
+func TestCopilotListModelsEndpoints(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" || r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"data":[
+			{"id":"gpt-5","name":"GPT-5","vendor":"OpenAI","capabilities":{"type":"chat","supports":{"vision":true,"tool_calls":true,"streaming":false}},"supported_endpoints":["/chat/completions","/responses"]},
+			{"id":"gpt-5.1","capabilities":{"type":"chat"}},
+			{"id":"claude-sonnet-4","capabilities":{"type":"chat"}},
+			{"id":"text-embedding-3-small","capabilities":{"type":"embeddings"}}
+		]}`)
+	}))
+	defer srv.Close()
+
+	cp := &copilotProvider{baseURL: srv.URL, token: "tok", model: "gpt-5-mini"}
+	models, err := cp.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if got := modelIDs(models); got != "claude-sonnet-4,gpt-5,gpt-5.1" {
+		t.Fatalf("models = %s", got)
+	}
+	want := map[string]string{"claude-sonnet-4": "chat", "gpt-5": "chat,responses", "gpt-5.1": "responses"}
+	for _, m := range models {
+		if got := strings.Join(m.Endpoints, ","); got != want[m.ID] {
+			t.Errorf("%s endpoints = %s, want %s", m.ID, got, want[m.ID])
+		}
+	}
+	if got := strings.Join(models[1].Capabilities, ","); got != "tool_calls,vision" {
+		t.Errorf("capabilities = %s", got)
+	}
+	if models[1].Vision == nil || !*models[1].Vision || models[0].Vision != nil {
+		t.Errorf("vision = %v, %v", models[1].Vision, models[0].Vision)
+	}
+}
+
+func TestListModelsHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"bad key"}}`)
+	}))
+	defer srv.Close()
+
+	p := &openAIProvider{endpoint: srv.URL, apiKey: "x"}
+	_, err := p.ListModels(context.Background())
+	var pe *ProviderError
+	if !errors.As(err, &pe) || pe.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected ProviderError 401, got %v", err)
+	}
+}
+
+func TestModelCacheRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	account := CredentialHash(map[string]string{"api-key": "sk-one"})
+	cache := ModelCache{Provider: "openai", Endpoint: "http://e", Account: account, Fetched: now, Models: []ModelInfo{{ID: "m1"}}}
+	if err := SaveModelCache(dir, cache); err != nil {
+		t.Fatalf("SaveModelCache: %v", err)
+	}
+	got, ok := LoadModelCache(dir, "openai", "http://e", account, now.Add(time.Hour))
+	if !ok || modelIDs(got.Models) != "m1" {
+		t.Fatalf("cache miss or wrong models: %v %+v", ok, got)
+	}
+	if _, ok := LoadModelCache(dir, "openai", "http://other", account, now); ok {
+		t.Fatalf("expected miss for another endpoint")
+	}
+	other := CredentialHash(map[string]string{"api-key": "sk-two"})
+	if other == account {
+		t.Fatalf("different keys hashed the same")
+	}
+	if _, ok := LoadModelCache(dir, "openai", "http://e", other, now); ok {
+		t.Fatalf("expected miss for another credential")
+	}
+	if _, ok := LoadModelCache(dir, "openai", "http://e", account, now.Add(ModelCacheTTL+time.Minute)); ok {
+		t.Fatalf("expected miss for expired cache")
+	}
+	data, _ := os.ReadFile(modelCachePath(dir, "openai", "http://e", account))
+	if strings.Contains(string(data), "sk-one") {
+		t.Fatalf("cache file contains the API key: %s", data)
+	}
+}
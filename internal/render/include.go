@@ -0,0 +1,223 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Include limits used when the caller does not set its own.
+const (
+	DefaultIncludeMaxBytes = 512 << 10
+	DefaultIncludeMaxDepth = 5
+)
+
+// includeActionPattern matches a {{include "path"}} template action.
+var includeActionPattern = regexp.MustCompile(`\{\{-?\s*include\s+("(?:[^"\\]|\\.)*")\s*-?\}\}`)
+
+// fileDirectivePattern matches an @file:path directive.  Trailing sentence
+// punctuation is not part of the path.
+var fileDirectivePattern = regexp.MustCompile(`@file:([^\s"'<>{}]*[^\s"'<>{}.,;:!?)])`)
+
+// Includer expands include directives: {{include "path"}} in templates and
+// @file:path anywhere in agent bodies.  Paths may be globs; the matched
+// files are joined with blank lines.  Included files may include others,
+// relative to themselves, up to MaxDepth levels.  Every included file must
+// lie inside one of Roots, so an agent cannot pull in files from outside the
+// workspace through ../ or symlinks.
+type Includer struct {
+	Dir      string   // directory relative paths in the top-level text resolve against
+	Roots    []string // directories included files must stay inside
+	MaxBytes int64    // total bytes included; 0 means DefaultIncludeMaxBytes
+	MaxDepth int      // nesting limit; 0 means DefaultIncludeMaxDepth
+
+	// Files lists every included file, in order.
+	Files []string
+
+	total int64
+	err   error
+}
+
+// RebaseIncludes rewrites the include directives in text, which was read
+// from a file in dir, into {{include}} actions with absolute paths.  Agent
+// bodies are rebased when loaded so that directives keep pointing at the
+// right files after an extends chain combines bodies from several
+// directories.
+func RebaseIncludes(text, dir string) string {
+	text = includeActionPattern.ReplaceAllStringFunc(text, func(m string) string {
+		path, err := strconv.Unquote(includeActionPattern.FindStringSubmatch(m)[1])
+		if err != nil {
+			return m
+		}
+		return includeAction(absPath(path, dir))
+	})
+	return fileDirectivePattern.ReplaceAllStringFunc(text, func(m string) string {
+		return includeAction(absPath(fileDirectivePattern.FindStringSubmatch(m)[1], dir))
+	})
+}
+
+func includeAction(path string) string {
+	return "{{include " + strconv.Quote(path) + "}}"
+}
+
+func absPath(path, dir string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
+
+// include returns the expanded content of path (relative to dir).  stack
+// holds the files currently being included, for cycle detection.
+func (inc *Includer) include(path, dir string, stack []string) (string, error) {
+	maxDepth := inc.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultIncludeMaxDepth
+	}
+	if len(stack) >= maxDepth {
+		return "", fmt.Errorf("include %s: nested deeper than %d levels", path, maxDepth)
+	}
+
+	pattern := absPath(path, dir)
+	matches := []string{pattern}
+	if strings.ContainsAny(pattern, "*?[") {
+		var err error
+		if matches, err = filepath.Glob(pattern); err != nil {
+			return "", fmt.Errorf("include %s: %w", path, err)
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("include %s: no files match", path)
+		}
+		sort.Strings(matches)
+	}
+
+	var parts []string
+	for _, file := range matches {
+		content, err := inc.readFile(file, stack)
+		if err != nil {
+			return "", fmt.Errorf("include %s: %w", path, err)
+		}
+		parts = append(parts, content)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// readFile reads one included file and expands its own directives.
+func (inc *Includer) readFile(file string, stack []string) (string, error) {
+	// Check the path as written first so ../ escapes are reported as such
+	// even when the target does not exist, then again once symlinks are
+	// resolved.
+	if !inc.insideRoots(file) {
+		return "", fmt.Errorf("%s is outside the workspace", file)
+	}
+	resolved, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return "", err
+	}
+	if !inc.insideRoots(resolved) {
+		return "", fmt.Errorf("%s is outside the workspace", file)
+	}
+	for _, open := range stack {
+		if open == resolved {
+			return "", fmt.Errorf("include cycle through %s", file)
+		}
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", file)
+	}
+
+	maxBytes := inc.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultIncludeMaxBytes
+	}
+	if inc.total+info.Size() > maxBytes {
+		return "", fmt.Errorf("included files exceed %d bytes (set include-max-bytes to raise the limit)", maxBytes)
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", fmt.Errorf("%s is not valid UTF-8 text", file)
+	}
+	inc.total += int64(len(data))
+	inc.Files = append(inc.Files, file)
+
+	return inc.expandNested(strings.TrimRight(string(data), "\n"), filepath.Dir(file), append(stack, resolved))
+}
+
+// Expand expands the include directives in text, relative to inc.Dir, and
+// keeps everything else verbatim.  It is used instead of Render for text
+// that is not a template.
+func (inc *Includer) Expand(text string) (string, error) {
+	return inc.expandNested(text, inc.Dir, nil)
+}
+
+// expandNested expands both directive forms in an included file.  Included
+// files are not templates, so their other {{...}} text is kept verbatim.
+func (inc *Includer) expandNested(text, dir string, stack []string) (string, error) {
+	var firstErr error
+	expand := func(path string) string {
+		if firstErr != nil {
+			return ""
+		}
+		content, err := inc.include(path, dir, stack)
+		if err != nil {
+			firstErr = err
+		}
+		return content
+	}
+	text = includeActionPattern.ReplaceAllStringFunc(text, func(m string) string {
+		path, err := strconv.Unquote(includeActionPattern.FindStringSubmatch(m)[1])
+		if err != nil {
+			return m
+		}
+		return expand(path)
+	})
+	text = fileDirectivePattern.ReplaceAllStringFunc(text, func(m string) string {
+		return expand(fileDirectivePattern.FindStringSubmatch(m)[1])
+	})
+	return text, firstErr
+}
+
+// insideRoots reports whether path lies inside one of the roots, taken both
+// as given and with symlinks resolved.
+func (inc *Includer) insideRoots(path string) bool {
+	for _, root := range inc.Roots {
+		candidates := []string{root}
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			candidates = append(candidates, resolved)
+		}
+		for _, dir := range candidates {
+			rel, err := filepath.Rel(dir, path)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// templateFunc returns the include function for templates.
+func (inc *Includer) templateFunc() func(string) (string, error) {
+	return func(path string) (string, error) {
+		if inc == nil {
+			return "", fmt.Errorf("include is only supported in agent files")
+		}
+		content, err := inc.include(path, inc.Dir, nil)
+		if err != nil && inc.err == nil {
+			inc.err = err
+		}
+		return content, err
+	}
+}
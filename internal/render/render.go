@@ -0,0 +1,132 @@
+// Package render expands Go text/template syntax in agent bodies and prompt
+// files.
+//
+// Templates see a flat map of string variables, referenced as {{.name}}.
+// Variables come from three layers, lowest precedence first: built-ins
+// (date, cwd, git_branch, os, model), agent frontmatter defaults, and --var
+// flags.  A variable that is referenced but never set is an error rather
+// than an empty string, so a forgotten --var cannot silently produce a
+// half-filled prompt.
+//
+// Templating is opt-in: callers render an agent body or prompt file only
+// when the agent asks for it or --var is given, so text that merely quotes
+// template syntax (Helm charts, GitHub Actions ${{ }}) is left alone.  Text
+// without "{{" is returned unchanged either way.
+//
+// Agent bodies may also pull in other files with {{include "path"}} or
+// @file:path; see Includer.
+package render
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// namePattern is the syntax of a variable name usable as {{.name}}.
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// missingKeyPattern extracts the variable name from text/template's
+// missingkey=error message.
+var missingKeyPattern = regexp.MustCompile(`map has no entry for key "([^"]+)"`)
+
+// ValidName reports whether name can be used as a template variable.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Builtins returns the built-in variables for a run in dir using model.
+func Builtins(dir, model string, now time.Time) map[string]string {
+	return map[string]string{
+		"date":       now.Format("2006-01-02"),
+		"cwd":        dir,
+		"git_branch": GitBranch(dir),
+		"os":         runtime.GOOS,
+		"model":      model,
+	}
+}
+
+// Merge combines variable layers; later layers win.
+func Merge(layers ...map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, layer := range layers {
+		for k, v := range layer {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Render executes text as a template named name (used in error messages)
+// with vars as data.  inc expands {{include}} actions; when it is nil they
+// are an error.  Included content is inserted verbatim, not rendered.
+func Render(name, text string, vars map[string]string, inc *Includer) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"include": inc.templateFunc()}).
+		Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		if inc != nil && inc.err != nil {
+			return "", fmt.Errorf("%s: %w", name, inc.err)
+		}
+		if m := missingKeyPattern.FindStringSubmatch(err.Error()); m != nil {
+			return "", fmt.Errorf("%s: missing required variable %q (set it with --var %s=<value>)", name, m[1], m[1])
+		}
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// GitBranch returns the checked-out branch of the git repository containing
+// dir, the short commit hash for a detached HEAD, or "" outside a repository.
+// It reads .git/HEAD directly rather than running git.
+func GitBranch(dir string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		gitDir := filepath.Join(d, ".git")
+		if info, err := os.Stat(gitDir); err == nil {
+			if !info.IsDir() {
+				// Worktrees and submodules use a "gitdir: <path>" file.
+				data, err := os.ReadFile(gitDir)
+				if err != nil {
+					return ""
+				}
+				target := strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(d, target)
+				}
+				gitDir = target
+			}
+			return headBranch(filepath.Join(gitDir, "HEAD"))
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return ""
+		}
+	}
+}
+
+func headBranch(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref:"); ok {
+		return strings.TrimPrefix(strings.TrimSpace(ref), "refs/heads/")
+	}
+	if len(head) > 7 {
+		return head[:7]
+	}
+	return head
+}
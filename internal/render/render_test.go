@@ -0,0 +1,190 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderVariables(t *testing.T) {
+	out, err := Render("agent.md", "Review {{.lang}} code on {{.git_branch}}.", map[string]string{"lang": "Go", "git_branch": "main"}, nil)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if out != "Review Go code on main." {
+		t.Fatalf("out = %q", out)
+	}
+}
+
+func TestRenderWithoutTemplateUnchanged(t *testing.T) {
+	text := "Use {braces} and .dots freely."
+	out, err := Render("agent.md", text, nil, nil)
+	if err != nil || out != text {
+		t.Fatalf("out = %q, err = %v", out, err)
+	}
+}
+
+func TestRenderMissingVariable(t *testing.T) {
+	_, err := Render("agent.md", "Hello {{.name}}", map[string]string{}, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	want := `agent.md: missing required variable "name" (set it with --var name=<value>)`
+	if err.Error() != want {
+		t.Fatalf("error = %q, want %q", err, want)
+	}
+}
+
+func TestRenderSyntaxError(t *testing.T) {
+	_, err := Render("agent.md", "Hello {{.name", map[string]string{"name": "x"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "agent.md") {
+		t.Fatalf("error = %v", err)
+	}
+}
+
+func TestMergePrecedence(t *testing.T) {
+	got := Merge(map[string]string{"a": "1", "b": "1"}, map[string]string{"b": "2"}, nil)
+	if got["a"] != "1" || got["b"] != "2" {
+		t.Fatalf("Merge = %v", got)
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for name, want := range map[string]bool{"lang": true, "_x1": true, "1x": false, "a-b": false, "": false} {
+		if ValidName(name) != want {
+			t.Errorf("ValidName(%q) = %v, want %v", name, !want, want)
+		}
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	vars := Builtins(dir, "gpt-x", now)
+	if vars["date"] != "2024-03-05" || vars["cwd"] != dir || vars["model"] != "gpt-x" || vars["os"] == "" {
+		t.Fatalf("Builtins = %v", vars)
+	}
+	if vars["git_branch"] != "" {
+		t.Fatalf("git_branch outside a repository = %q", vars["git_branch"])
+	}
+}
+
+func TestGitBranch(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".git"), 0o755)
+	os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/feature/x\n"), 0o644)
+	sub := filepath.Join(dir, "a", "b")
+	os.MkdirAll(sub, 0o755)
+	if got := GitBranch(sub); got != "feature/x" {
+		t.Fatalf("GitBranch = %q", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("0123456789abcdef\n"), 0o644)
+	if got := GitBranch(dir); got != "0123456" {
+		t.Fatalf("detached GitBranch = %q", got)
+	}
+
+	wt := t.TempDir()
+	os.WriteFile(filepath.Join(wt, ".git"), []byte("gitdir: "+filepath.Join(dir, ".git")+"\n"), 0o644)
+	if got := GitBranch(wt); got != "0123456" {
+		t.Fatalf("worktree GitBranch = %q", got)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRenderInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "docs", "style.md"), "Use {{tabs}}.\n@file:naming.md\n")
+	writeFile(t, filepath.Join(dir, "docs", "naming.md"), "Short names.\n")
+	inc := &Includer{Dir: dir, Roots: []string{dir}}
+
+	text := RebaseIncludes("Rules for {{.lang}}:\n@file:docs/style.md\n", dir)
+	out, err := Render("agent.md", text, map[string]string{"lang": "Go"}, inc)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	// Included files are inserted verbatim; their own directives are expanded
+	// relative to themselves.
+	if out != "Rules for Go:\nUse {{tabs}}.\nShort names.\n" {
+		t.Fatalf("out = %q", out)
+	}
+	if len(inc.Files) != 2 {
+		t.Fatalf("Files = %v", inc.Files)
+	}
+}
+
+func TestRenderIncludeGlob(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "rules", "b.md"), "B\n")
+	writeFile(t, filepath.Join(dir, "rules", "a.md"), "A\n")
+	out, err := Render("agent.md", `{{include "rules/*.md"}}`, nil, &Includer{Dir: dir, Roots: []string{dir}})
+	if err != nil || out != "A\n\nB" {
+		t.Fatalf("out = %q, err = %v", out, err)
+	}
+}
+
+func TestRenderIncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(outside, "secret.txt"), "secret\n")
+	os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(dir, "link.txt"))
+	writeFile(t, filepath.Join(dir, "a.md"), "@file:b.md\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "@file:a.md\n")
+	writeFile(t, filepath.Join(dir, "big.md"), strings.Repeat("x", 100))
+	writeFile(t, filepath.Join(dir, "bin.dat"), "\x00\x01")
+
+	cases := map[string]string{
+		`{{include "` + filepath.Join(outside, "secret.txt") + `"}}`: "outside the workspace",
+		`{{include "link.txt"}}`:     "outside the workspace",
+		`{{include "a.md"}}`:         "include cycle",
+		`{{include "big.md"}}`:       "exceed 50 bytes",
+		`{{include "bin.dat"}}`:      "not valid UTF-8",
+		`{{include "missing/*.md"}}`: "no files match",
+	}
+	for text, want := range cases {
+		_, err := Render("agent.md", text, nil, &Includer{Dir: dir, Roots: []string{dir}, MaxBytes: 50})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want %q", text, err, want)
+		}
+	}
+
+	if _, err := Render("prompt.txt", `{{include "a.md"}}`, nil, nil); err == nil {
+		t.Error("expected include without an Includer to fail")
+	}
+}
+
+func TestRenderIncludeDepth(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 4; i++ {
+		writeFile(t, filepath.Join(dir, fmt.Sprintf("%d.md", i)), fmt.Sprintf("@file:%d.md", i+1))
+	}
+	writeFile(t, filepath.Join(dir, "4.md"), "done")
+	inc := &Includer{Dir: dir, Roots: []string{dir}, MaxDepth: 3}
+	if _, err := Render("agent.md", `{{include "0.md"}}`, nil, inc); err == nil || !strings.Contains(err.Error(), "deeper than 3") {
+		t.Fatalf("error = %v", err)
+	}
+	out, err := Render("agent.md", `{{include "0.md"}}`, nil, &Includer{Dir: dir, Roots: []string{dir}})
+	if err != nil || out != "done" {
+		t.Fatalf("out = %q, err = %v", out, err)
+	}
+}
+
+func TestRebaseIncludes(t *testing.T) {
+	got := RebaseIncludes("See @file:docs/a.md. And {{include \"/abs/b.md\"}}\nmail me@example.com", "/agents")
+	want := "See {{include \"/agents/docs/a.md\"}}. And {{include \"/abs/b.md\"}}\nmail me@example.com"
+	if got != want {
+		t.Fatalf("RebaseIncludes = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,523 @@
+// Package schema validates JSON values against a JSON Schema.
+//
+// It implements the subset of JSON Schema (draft 2020-12) that structured
+// model output and tool arguments use in practice: type, enum, const,
+// properties, required, additionalProperties, items, min/max constraints,
+// pattern, allOf/anyOf/oneOf/not, and local $ref pointers into $defs or
+// definitions.  Unknown keywords are ignored, as the specification requires.
+//
+// Why not a third-party validator?  rai keeps its dependency set minimal and
+// only needs to report readable errors back to the model, not to support
+// every draft and vocabulary.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a parsed JSON Schema document.
+type Schema struct {
+	raw      json.RawMessage
+	root     any
+	patterns map[string]*regexp.Regexp
+}
+
+// Error describes one validation failure.
+type Error struct {
+	Path    string // JSON path of the offending value, e.g. "$.items[0].name"
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Errors is the list of failures returned by Validate.
+type Errors []Error
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, err := range e {
+		parts[i] = err.Error()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Load reads and parses a schema file.
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse parses a schema document.  The root must be a JSON object (or the
+// boolean schemas true/false); regular expressions are compiled up front so
+// a broken pattern is reported once, at load time.
+func Parse(data []byte) (*Schema, error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %w", err)
+	}
+	switch root.(type) {
+	case map[string]any, bool:
+	default:
+		return nil, fmt.Errorf("schema must be a JSON object")
+	}
+	s := &Schema{raw: compact(data), root: root, patterns: map[string]*regexp.Regexp{}}
+	if err := s.compilePatterns(root); err != nil {
+		return nil, err
+	}
+	if err := s.checkRefCycles(root, map[string]int{}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Raw returns the compact JSON encoding of the schema.
+func (s *Schema) Raw() json.RawMessage {
+	return s.raw
+}
+
+// ValidateJSON decodes text as JSON and validates it.  The returned error is
+// either a decode error or Errors.
+func (s *Schema) ValidateJSON(text string) error {
+	var v any
+	dec := json.NewDecoder(strings.NewReader(text))
+	if err := dec.Decode(&v); err != nil {
+		return Errors{{Path: "$", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	if dec.More() {
+		return Errors{{Path: "$", Message: "unexpected data after JSON value"}}
+	}
+	return s.Validate(v)
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json into
+// an any) and returns Errors, or nil when the value conforms.
+func (s *Schema) Validate(v any) error {
+	var errs Errors
+	s.validate(s.root, v, "$", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (s *Schema) validate(node, v any, path string, errs *Errors) {
+	switch n := node.(type) {
+	case bool:
+		if !n {
+			*errs = append(*errs, Error{path, "no value is allowed here"})
+		}
+		return
+	case map[string]any:
+		s.validateObject(n, v, path, errs)
+	}
+}
+
+func (s *Schema) validateObject(n map[string]any, v any, path string, errs *Errors) {
+	if ref, ok := n["$ref"].(string); ok {
+		target, err := s.resolve(ref)
+		if err != nil {
+			*errs = append(*errs, Error{path, err.Error()})
+			return
+		}
+		s.validate(target, v, path, errs)
+	}
+
+	if t, ok := n["type"]; ok && !matchesType(t, v) {
+		*errs = append(*errs, Error{path, fmt.Sprintf("expected %s, got %s", describeType(t), typeOf(v))})
+		return
+	}
+	if enum, ok := n["enum"].([]any); ok && !containsValue(enum, v) {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must be one of %s", formatValues(enum))})
+	}
+	if c, ok := n["const"]; ok && !equal(c, v) {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must equal %s", formatValue(c))})
+	}
+
+	switch val := v.(type) {
+	case string:
+		s.validateString(n, val, path, errs)
+	case float64:
+		validateNumber(n, val, path, errs)
+	case []any:
+		s.validateArray(n, val, path, errs)
+	case map[string]any:
+		s.validateProperties(n, val, path, errs)
+	}
+
+	if all, ok := n["allOf"].([]any); ok {
+		for _, sub := range all {
+			s.validate(sub, v, path, errs)
+		}
+	}
+	if anyOf, ok := n["anyOf"].([]any); ok && s.countMatches(anyOf, v, path) == 0 {
+		*errs = append(*errs, Error{path, "does not match any of the allowed schemas"})
+	}
+	if oneOf, ok := n["oneOf"].([]any); ok {
+		if count := s.countMatches(oneOf, v, path); count != 1 {
+			*errs = append(*errs, Error{path, fmt.Sprintf("must match exactly one schema, matched %d", count)})
+		}
+	}
+	if not, ok := n["not"]; ok {
+		var sub Errors
+		s.validate(not, v, path, &sub)
+		if len(sub) == 0 {
+			*errs = append(*errs, Error{path, "matches a disallowed schema"})
+		}
+	}
+}
+
+func (s *Schema) validateString(n map[string]any, v, path string, errs *Errors) {
+	length := len([]rune(v))
+	if min, ok := number(n["minLength"]); ok && float64(length) < min {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must be at least %v characters", min)})
+	}
+	if max, ok := number(n["maxLength"]); ok && float64(length) > max {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must be at most %v characters", max)})
+	}
+	if pattern, ok := n["pattern"].(string); ok {
+		if re := s.patterns[pattern]; re != nil && !re.MatchString(v) {
+			*errs = append(*errs, Error{path, fmt.Sprintf("must match pattern %q", pattern)})
+		}
+	}
+}
+
+func validateNumber(n map[string]any, v float64, path string, errs *Errors) {
+	if min, ok := number(n["minimum"]); ok && v < min {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must be >= %v", min)})
+	}
+	if max, ok := number(n["maximum"]); ok && v > max {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must be <= %v", max)})
+	}
+	if min, ok := number(n["exclusiveMinimum"]); ok && v <= min {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must be > %v", min)})
+	}
+	if max, ok := number(n["exclusiveMaximum"]); ok && v >= max {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must be < %v", max)})
+	}
+	if mult, ok := number(n["multipleOf"]); ok && mult > 0 {
+		if q := v / mult; math.Abs(q-math.Round(q)) > 1e-9 {
+			*errs = append(*errs, Error{path, fmt.Sprintf("must be a multiple of %v", mult)})
+		}
+	}
+}
+
+func (s *Schema) validateArray(n map[string]any, v []any, path string, errs *Errors) {
+	if min, ok := number(n["minItems"]); ok && float64(len(v)) < min {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must have at least %v items", min)})
+	}
+	if max, ok := number(n["maxItems"]); ok && float64(len(v)) > max {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must have at most %v items", max)})
+	}
+	if unique, _ := n["uniqueItems"].(bool); unique {
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if equal(v[i], v[j]) {
+					*errs = append(*errs, Error{path, fmt.Sprintf("items %d and %d are equal", i, j)})
+				}
+			}
+		}
+	}
+	if items, ok := n["items"]; ok {
+		for i, item := range v {
+			s.validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func (s *Schema) validateProperties(n map[string]any, v map[string]any, path string, errs *Errors) {
+	if required, ok := n["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := v[name]; name != "" && !present {
+				*errs = append(*errs, Error{path, fmt.Sprintf("missing required property %q", name)})
+			}
+		}
+	}
+	if min, ok := number(n["minProperties"]); ok && float64(len(v)) < min {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must have at least %v properties", min)})
+	}
+	if max, ok := number(n["maxProperties"]); ok && float64(len(v)) > max {
+		*errs = append(*errs, Error{path, fmt.Sprintf("must have at most %v properties", max)})
+	}
+
+	props, _ := n["properties"].(map[string]any)
+	additional, hasAdditional := n["additionalProperties"]
+
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		childPath := path + "." + key
+		if sub, ok := props[key]; ok {
+			s.validate(sub, v[key], childPath, errs)
+			continue
+		}
+		if !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			*errs = append(*errs, Error{path, fmt.Sprintf("unexpected property %q", key)})
+			continue
+		}
+		s.validate(additional, v[key], childPath, errs)
+	}
+}
+
+func (s *Schema) countMatches(schemas []any, v any, path string) int {
+	count := 0
+	for _, sub := range schemas {
+		var subErrs Errors
+		s.validate(sub, v, path, &subErrs)
+		if len(subErrs) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// resolve follows a local JSON pointer such as "#/$defs/item".
+func (s *Schema) resolve(ref string) (any, error) {
+	if ref == "#" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q (only local references are allowed)", ref)
+	}
+	node := s.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if node, ok = obj[part]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return node, nil
+}
+
+func (s *Schema) compilePatterns(node any) error {
+	switch n := node.(type) {
+	case map[string]any:
+		for key, value := range n {
+			if p, ok := value.(string); ok && key == "pattern" {
+				re, err := regexp.Compile(p)
+				if err != nil {
+					return fmt.Errorf("invalid pattern %q: %w", p, err)
+				}
+				s.patterns[p] = re
+				continue
+			}
+			if err := s.compilePatterns(value); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range n {
+			if err := s.compilePatterns(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Ref states for checkRefCycles.
+const (
+	refFollowing = 1 // on the current chain
+	refDone      = 2 // known to end without a cycle
+)
+
+// checkRefCycles rejects $ref chains that lead back to a schema already
+// being applied to the same value, such as {"$ref": "#"}: validation would
+// follow them forever.  Every schema in the document is a starting point;
+// refs keeps the state of each $ref across them.
+func (s *Schema) checkRefCycles(node any, refs map[string]int) error {
+	switch n := node.(type) {
+	case map[string]any:
+		if err := s.followInPlace(n, refs); err != nil {
+			return err
+		}
+		for _, value := range n {
+			if err := s.checkRefCycles(value, refs); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range n {
+			if err := s.checkRefCycles(item, refs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// followInPlace follows the keywords that apply another schema to the same
+// value ($ref, allOf, anyOf, oneOf, not) and reports a $ref met twice on
+// the way.  Unresolvable refs are left for validation to report.
+func (s *Schema) followInPlace(node any, refs map[string]int) error {
+	n, ok := node.(map[string]any)
+	if !ok {
+		return nil
+	}
+	if ref, ok := n["$ref"].(string); ok {
+		switch refs[ref] {
+		case refFollowing:
+			return fmt.Errorf("$ref %q refers back to itself without descending into the value", ref)
+		case 0:
+			refs[ref] = refFollowing
+			if target, err := s.resolve(ref); err == nil {
+				if err := s.followInPlace(target, refs); err != nil {
+					return err
+				}
+			}
+			refs[ref] = refDone
+		}
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		subs, _ := n[key].([]any)
+		for _, sub := range subs {
+			if err := s.followInPlace(sub, refs); err != nil {
+				return err
+			}
+		}
+	}
+	return s.followInPlace(n["not"], refs)
+}
+
+func matchesType(t, v any) bool {
+	switch tt := t.(type) {
+	case string:
+		return isType(tt, v)
+	case []any:
+		for _, item := range tt {
+			if name, ok := item.(string); ok && isType(name, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func isType(name string, v any) bool {
+	switch name {
+	case "null":
+		return v == nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	}
+	return false
+}
+
+func describeType(t any) string {
+	if list, ok := t.([]any); ok {
+		names := make([]string, 0, len(list))
+		for _, item := range list {
+			names = append(names, fmt.Sprint(item))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func typeOf(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func number(v any) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
+
+func containsValue(values []any, v any) bool {
+	for _, candidate := range values {
+		if equal(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+func formatValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func formatValues(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = formatValue(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func compact(data []byte) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return json.RawMessage(data)
+	}
+	return json.RawMessage(buf.Bytes())
+}
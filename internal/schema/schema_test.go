@@ -0,0 +1,140 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const personSchema = `{
+  "type": "object",
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "age": {"type": "integer", "minimum": 0},
+    "role": {"enum": ["admin", "user"]},
+    "tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+    "address": {"$ref": "#/$defs/address"}
+  },
+  "required": ["name", "age"],
+  "additionalProperties": false,
+  "$defs": {
+    "address": {
+      "type": "object",
+      "properties": {"zip": {"type": "string", "pattern": "^[0-9]{5}$"}},
+      "required": ["zip"]
+    }
+  }
+}`
+
+func mustParse(t *testing.T, src string) *Schema {
+	t.Helper()
+	s, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return s
+}
+
+func TestValidateJSONAccepts(t *testing.T) {
+	s := mustParse(t, personSchema)
+	if err := s.ValidateJSON(`{"name":"Ada","age":36,"role":"admin","tags":["x"],"address":{"zip":"12345"}}`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateJSONReportsPaths(t *testing.T) {
+	s := mustParse(t, personSchema)
+	err := s.ValidateJSON(`{"name":"","age":1.5,"role":"root","tags":["a",2,"c"],"address":{"zip":"abc"},"extra":true}`)
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+	msg := errs.Error()
+	for _, want := range []string{
+		"$.name: must be at least 1 characters",
+		"$.age: expected integer, got number",
+		"$.role: must be one of \"admin\", \"user\"",
+		"$.tags: must have at most 2 items",
+		"$.tags[1]: expected string, got integer",
+		"$.address.zip: must match pattern",
+		"$: unexpected property \"extra\"",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in %q", want, msg)
+		}
+	}
+}
+
+func TestValidateJSONMissingRequired(t *testing.T) {
+	s := mustParse(t, personSchema)
+	err := s.ValidateJSON(`{"name":"Ada"}`)
+	if err == nil || !strings.Contains(err.Error(), `missing required property "age"`) {
+		t.Fatalf("expected missing property error, got %v", err)
+	}
+}
+
+func TestValidateJSONRejectsNonJSON(t *testing.T) {
+	s := mustParse(t, `{"type":"object"}`)
+	if err := s.ValidateJSON("Sure! Here it is."); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Fatalf("expected invalid JSON error, got %v", err)
+	}
+	if err := s.ValidateJSON(`{} {}`); err == nil {
+		t.Fatalf("expected trailing data error")
+	}
+}
+
+func TestValidateCombinators(t *testing.T) {
+	s := mustParse(t, `{"oneOf":[{"type":"string"},{"type":"integer"}],"not":{"const":"no"}}`)
+	if err := s.ValidateJSON(`"yes"`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.ValidateJSON(`"no"`); err == nil {
+		t.Fatalf("expected not violation")
+	}
+	if err := s.ValidateJSON(`true`); err == nil {
+		t.Fatalf("expected oneOf violation")
+	}
+}
+
+func TestParseRejectsInvalidSchemas(t *testing.T) {
+	for _, src := range []string{`[1]`, `not json`, `{"pattern":"("}`} {
+		if _, err := Parse([]byte(src)); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
+
+func TestParseRejectsRefCycles(t *testing.T) {
+	for _, src := range []string{
+		`{"$ref":"#"}`,
+		`{"$ref":"#/$defs/a","$defs":{"a":{"$ref":"#/$defs/b"},"b":{"allOf":[{"$ref":"#/$defs/a"}]}}}`,
+		`{"type":"object","properties":{"x":{"not":{"$ref":"#/properties/x"}}}}`,
+	} {
+		if _, err := Parse([]byte(src)); err == nil || !strings.Contains(err.Error(), "refers back to itself") {
+			t.Errorf("%s: error = %v", src, err)
+		}
+	}
+
+	// Recursion through properties or items descends into the value.
+	tree := mustParse(t, `{"$ref":"#/$defs/node","$defs":{"node":{"type":"object","properties":{"children":{"type":"array","items":{"$ref":"#/$defs/node"}}},"allOf":[{"$ref":"#/$defs/named"}]},"named":{"required":["name"]}}}`)
+	if err := tree.ValidateJSON(`{"name":"a","children":[{"name":"b","children":[]}]}`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tree.ValidateJSON(`{"name":"a","children":[{}]}`); err == nil || !strings.Contains(err.Error(), "$.children[0]") {
+		t.Fatalf("expected nested error, got %v", err)
+	}
+}
+
+func TestLoadCompactsRaw(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.json")
+	os.WriteFile(path, []byte("{\n  \"type\": \"object\"\n}\n"), 0o644)
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if string(s.Raw()) != `{"type":"object"}` {
+		t.Fatalf("Raw = %s", s.Raw())
+	}
+}
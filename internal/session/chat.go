@@ -0,0 +1,55 @@
+package session
+
+import (
+	"context"
+
+	"run-ai/internal/provider"
+)
+
+// Chat is a multi-turn conversation.  Unlike Run, which discards history when
+// it returns, Chat keeps every user, assistant and tool message so later turns
+// see the full context.  Tool calls and sink events behave exactly as in Run.
+type Chat struct {
+	cfg     Config
+	history []provider.Message
+}
+
+// NewChat starts a conversation seeded with cfg's system prompt and skill
+// context, or with cfg.History when continuing a saved session.
+// cfg.UserPrompt is ignored; turns are supplied through Send.
+func NewChat(cfg Config) *Chat {
+	c := &Chat{cfg: cfg}
+	c.history = systemMessages(cfg)
+	return c
+}
+
+// Send appends prompt (and any images) as a user turn and runs it to a final
+// response.  On error the turn is discarded so the history stays consistent.
+func (c *Chat) Send(ctx context.Context, prompt string, images ...provider.ContentPart) error {
+	pending := append(c.History(), userMessage(prompt, images))
+	updated, err := converse(ctx, c.cfg, pending)
+	if err != nil {
+		return err
+	}
+	c.history = updated
+	return nil
+}
+
+// Reset clears the conversation back to a fresh system preamble, dropping
+// any continued history.
+func (c *Chat) Reset() {
+	c.cfg.History = nil
+	c.history = systemMessages(c.cfg)
+}
+
+// SetProvider switches the backend for subsequent turns, keeping history.
+func (c *Chat) SetProvider(p provider.Provider) {
+	c.cfg.Provider = p
+}
+
+// History returns a copy of the conversation so far.
+func (c *Chat) History() []provider.Message {
+	out := make([]provider.Message, len(c.history))
+	copy(out, c.history)
+	return out
+}
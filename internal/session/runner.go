@@ -22,11 +22,14 @@ import (
 
 	"run-ai/internal/output"
 	"run-ai/internal/provider"
+	"run-ai/internal/schema"
 	"run-ai/internal/skills"
 )
 
 const maxToolIterations = 10
 const terminalToolName = "terminal"
+const delegateToolName = "delegate"
+const readResourceToolName = "read_skill_resource"
 
 // Config holds everything the runner needs to execute one session.
 type Config struct {
@@ -34,25 +37,116 @@ type Config struct {
 	Sink         *output.Sink
 	SystemPrompt string
 	UserPrompt   string
+	Images       []provider.ContentPart // image parts sent with UserPrompt
 	Skills       []skills.Skill
 	BaseDir      string
+	Options      provider.GenerationOptions
+
+	// Tools, when non-nil, is the allowlist of tools offered to the model:
+	// "terminal" and skill names.  DisableTools offers no tools at all.
+	// Skills that are not offered are left out of the system prompt too, and
+	// calls to tools that were not offered are refused rather than run.
+	Tools        []string
+	DisableTools bool
+
+	// Delegate, when set, offers the model the delegate tool, which hands a
+	// task to another agent and returns that agent's final answer.
+	Delegate *Delegate
+
+	// MaxIterations bounds the tool loop; 0 means the default of 10.
+	MaxIterations int
+
+	// History, when non-empty, is a previous conversation to continue.  It
+	// replaces the system preamble built from SystemPrompt and Skills.
+	History []provider.Message
+
+	// ResponseSchema, when set, is the JSON Schema the final answer must
+	// match.  A non-conforming answer is sent back to the model with the
+	// validation errors up to SchemaRetries times before the run fails with
+	// a *SchemaError.
+	ResponseSchema *schema.Schema
+	SchemaRetries  int
 }
 
+// Delegate configures the delegate tool.  Run executes task with the named
+// agent in a nested session and returns its final answer; limiting how deep
+// delegation nests is up to Run, typically by leaving Delegate unset in
+// sessions at the maximum depth.
+type Delegate struct {
+	Agents []DelegateAgent
+	Run    func(ctx context.Context, agent, task string) (string, error)
+}
+
+// DelegateAgent is an agent the model may delegate to.
+type DelegateAgent struct {
+	Name        string
+	Description string
+}
+
+// SchemaError reports a final answer that still did not match the response
+// schema after every retry.
+type SchemaError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("response does not match schema after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+func (e *SchemaError) Unwrap() error { return e.Err }
+
 // Run executes a single prompt session: send to provider, stream output,
 // handle tool calls, and repeat until a final text response is produced.
 func Run(ctx context.Context, cfg Config) error {
-	messages := buildMessages(cfg)
+	_, err := RunConversation(ctx, cfg)
+	return err
+}
+
+// RunConversation is Run, but also returns the full message history of the
+// session (preamble, user turn, assistant and tool messages) for persistence.
+func RunConversation(ctx context.Context, cfg Config) ([]provider.Message, error) {
+	return converse(ctx, cfg, buildMessages(cfg))
+}
+
+// converse runs the tool loop over messages, which must end with the pending
+// user turn.  It returns the history extended with every assistant and tool
+// message produced, including the final assistant response.
+func converse(ctx context.Context, cfg Config, messages []provider.Message) ([]provider.Message, error) {
+	if err := checkToolAllowlist(cfg); err != nil {
+		cfg.Sink.Emit(output.EventERR, err.Error())
+		return messages, err
+	}
+	tools := buildToolDefs(cfg)
+	if err := checkToolChoice(cfg.Options.ToolChoice, tools); err != nil {
+		cfg.Sink.Emit(output.EventERR, err.Error())
+		return messages, err
+	}
 
-	for i := 0; i < maxToolIterations; i++ {
+	iterations := cfg.MaxIterations
+	if iterations <= 0 {
+		iterations = maxToolIterations
+	}
+	schemaFailures := 0
+	for i := 0; i < iterations; i++ {
 		req := provider.Request{
 			Messages: messages,
-			Tools:    buildToolDefs(cfg.Skills),
+			Tools:    tools,
+		}
+		cfg.Options.Apply(&req)
+		if cfg.ResponseSchema != nil {
+			req.ResponseSchema = cfg.ResponseSchema.Raw()
+		}
+		// A forced tool choice applies to the first request of a turn only;
+		// repeating it would keep the model from ever giving a text answer.
+		if i > 0 && cfg.Options.IsForced() {
+			req.ToolChoice = provider.ToolChoiceAuto
 		}
 
 		ch, err := cfg.Provider.Stream(ctx, req)
 		if err != nil {
 			cfg.Sink.Emit(output.EventERR, fmt.Sprintf("provider error: %v", err))
-			return err
+			return messages, err
 		}
 
 		var fullText string
@@ -66,7 +160,7 @@ func Run(ctx context.Context, cfg Config) error {
 					cfg.Sink.EndAIStream(fullText)
 				}
 				cfg.Sink.Emit(output.EventERR, fmt.Sprintf("stream error: %v", ev.Error))
-				return ev.Error
+				return messages, ev.Error
 			}
 			if ev.Text != "" {
 				fullText += ev.Text
@@ -94,14 +188,36 @@ func Run(ctx context.Context, cfg Config) error {
 			reasoningSummary = inferReasoningSummary(fullText)
 		}
 
-		if fullText != "" && !(cfg.Sink.IsSilent() && len(toolCalls) == 0) {
+		// Silent and JSON output report the final response through EmitFinal,
+		// which also logs it.
+		finalViaSink := cfg.Sink.IsSilent() || cfg.Sink.IsJSON()
+		if fullText != "" && !(finalViaSink && len(toolCalls) == 0) {
 			cfg.Sink.EmitLog(output.EventAI, fullText)
 		}
 
 		// No tool calls — emit the final response and return.
 		if len(toolCalls) == 0 {
-			if cfg.Sink.IsSilent() {
-				cfg.Sink.EmitFinal(fullText)
+			answer := fullText
+			if cfg.ResponseSchema != nil {
+				answer = extractJSON(fullText)
+				if verr := cfg.ResponseSchema.ValidateJSON(answer); verr != nil {
+					if finalViaSink && fullText != "" {
+						cfg.Sink.EmitLog(output.EventAI, fullText)
+					}
+					messages = append(messages, provider.Message{Role: "assistant", Content: fullText})
+					schemaFailures++
+					if schemaFailures > cfg.SchemaRetries {
+						err := &SchemaError{Attempts: schemaFailures, Err: verr}
+						cfg.Sink.Emit(output.EventERR, err.Error())
+						return messages, err
+					}
+					cfg.Sink.Emit(output.EventERR, fmt.Sprintf("response does not match schema, retrying: %v", verr))
+					messages = append(messages, provider.Message{Role: "user", Content: schemaRetryPrompt(verr)})
+					continue
+				}
+			}
+			if finalViaSink {
+				cfg.Sink.EmitFinal(answer)
 			}
 			if reasoningSummary != "" {
 				if cfg.Sink.IsSilent() {
@@ -110,7 +226,8 @@ func Run(ctx context.Context, cfg Config) error {
 					cfg.Sink.Emit(output.EventReasoning, reasoningSummary)
 				}
 			}
-			return nil
+			messages = append(messages, provider.Message{Role: "assistant", Content: fullText})
+			return messages, nil
 		}
 
 		if reasoningSummary != "" {
@@ -130,6 +247,16 @@ func Run(ctx context.Context, cfg Config) error {
 
 		// Execute each tool call.
 		for _, tc := range toolCalls {
+			if !offered(tools, tc.Name) {
+				errMsg := fmt.Sprintf("tool error: tool %q is not available", tc.Name)
+				cfg.Sink.EmitTool(output.EventERR, errMsg, output.ToolInfo{Name: tc.Name, Arguments: tc.Arguments})
+				messages = append(messages, provider.Message{
+					Role:       "tool",
+					Content:    fmt.Sprintf("[%s result]\n%s", tc.Name, errMsg),
+					ToolCallID: tc.ID,
+				})
+				continue
+			}
 			cmdLabel := fmt.Sprintf("tool: %s(%s)", tc.Name, tc.Arguments)
 			if tc.Name == terminalToolName {
 				args, err := parseTerminalArgs(tc.Arguments)
@@ -143,21 +270,63 @@ func Run(ctx context.Context, cfg Config) error {
 				}
 				cmdLabel = args.Command
 			}
-			cfg.Sink.Emit(output.EventCMD, cmdLabel)
+			if s, ok := findSkill(cfg, tc.Name); ok {
+				call, err := parseSkillCall(s, tc.Arguments)
+				if err != nil {
+					cfg.Sink.EmitTool(output.EventERR, fmt.Sprintf("tool error: %v", err), output.ToolInfo{Name: tc.Name, Arguments: tc.Arguments})
+					messages = append(messages, provider.Message{
+						Role:       "tool",
+						Content:    fmt.Sprintf("[%s result]\n%s", tc.Name, err.Error()),
+						ToolCallID: tc.ID,
+					})
+					continue
+				}
+				cmdLabel = skillCommand(tc.Name, call)
+			}
+			if tc.Name == readResourceToolName {
+				if args, _, err := parseReadResourceArgs(tc.Arguments, cfg); err == nil {
+					cmdLabel = fmt.Sprintf("%s %s/%s", readResourceToolName, args.Skill, args.Path)
+				}
+			}
+			var delegated delegateArgs
+			if tc.Name == delegateToolName {
+				args, err := parseDelegateArgs(tc.Arguments, cfg.Delegate)
+				if err != nil {
+					cfg.Sink.EmitTool(output.EventERR, fmt.Sprintf("tool error: %v", err), output.ToolInfo{Name: tc.Name, Arguments: tc.Arguments})
+					messages = append(messages, provider.Message{
+						Role:       "tool",
+						Content:    fmt.Sprintf("[%s result]\n%s", tc.Name, err.Error()),
+						ToolCallID: tc.ID,
+					})
+					continue
+				}
+				delegated = args
+				cmdLabel = fmt.Sprintf("delegate to %s: %s", args.Agent, args.Task)
+			}
+			tool := output.ToolInfo{Name: tc.Name, Arguments: tc.Arguments}
+			cfg.Sink.EmitTool(output.EventCMD, cmdLabel, tool)
 
-			result, err := executeToolCall(tc, cfg)
+			var result string
+			var err error
+			if tc.Name == delegateToolName {
+				result, err = cfg.Delegate.Run(ctx, delegated.Agent, delegated.Task)
+			} else {
+				result, err = executeToolCall(tc, cfg)
+			}
+			exitCode := toolExitCode(err)
+			tool.ExitCode = &exitCode
 			toolResult := result
 			if err != nil {
 				errMsg := fmt.Sprintf("tool error: %v", err)
-				cfg.Sink.Emit(output.EventERR, errMsg)
+				cfg.Sink.EmitTool(output.EventERR, errMsg, tool)
 				if result != "" {
-					cfg.Sink.Emit(output.EventOUT, result)
+					cfg.Sink.EmitTool(output.EventOUT, result, tool)
 					toolResult = errMsg + "\n" + result
 				} else {
 					toolResult = errMsg
 				}
 			} else {
-				cfg.Sink.Emit(output.EventOUT, result)
+				cfg.Sink.EmitTool(output.EventOUT, result, tool)
 			}
 
 			// Feed tool result back into conversation.
@@ -170,10 +339,67 @@ func Run(ctx context.Context, cfg Config) error {
 	}
 
 	cfg.Sink.Emit(output.EventERR, "maximum tool call iterations reached")
-	return fmt.Errorf("exceeded %d tool call iterations", maxToolIterations)
+	return messages, fmt.Errorf("exceeded %d tool call iterations", iterations)
+}
+
+// extractJSON strips surrounding whitespace and a Markdown code fence, which
+// models add around JSON answers even when asked not to.
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+	body := strings.TrimSuffix(text, "```")
+	if nl := strings.Index(body, "\n"); nl >= 0 {
+		body = body[nl+1:]
+	} else {
+		body = strings.TrimPrefix(body, "```")
+	}
+	return strings.TrimSpace(body)
+}
+
+// schemaRetryPrompt asks the model to correct an answer that failed
+// validation, listing each error on its own line.
+func schemaRetryPrompt(err error) string {
+	var b strings.Builder
+	b.WriteString("Your previous response did not match the required JSON schema:\n")
+	var errs schema.Errors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			fmt.Fprintf(&b, "- %s\n", e.Error())
+		}
+	} else {
+		fmt.Fprintf(&b, "- %v\n", err)
+	}
+	b.WriteString("Respond again with only the corrected JSON value.")
+	return b.String()
 }
 
 func buildMessages(cfg Config) []provider.Message {
+	msgs := systemMessages(cfg)
+	msgs = append(msgs, userMessage(cfg.UserPrompt, cfg.Images))
+	return msgs
+}
+
+// userMessage builds a user turn, with typed parts when images are attached.
+func userMessage(prompt string, images []provider.ContentPart) provider.Message {
+	msg := provider.Message{Role: "user", Content: prompt}
+	if len(images) > 0 {
+		msg.Parts = append([]provider.ContentPart{{Type: provider.PartText, Text: prompt}}, images...)
+	}
+	return msg
+}
+
+// systemMessages returns the conversation preamble: the agent instructions
+// combined with skill context, or nothing when both are empty.  A continued
+// session starts from its saved history instead.
+func systemMessages(cfg Config) []provider.Message {
+	if len(cfg.History) > 0 {
+		msgs := make([]provider.Message, len(cfg.History))
+		copy(msgs, cfg.History)
+		return msgs
+	}
+
 	var msgs []provider.Message
 
 	// System prompt: combine agent instructions + skill context.
@@ -181,8 +407,8 @@ func buildMessages(cfg Config) []provider.Message {
 	if cfg.SystemPrompt != "" {
 		systemParts = cfg.SystemPrompt
 	}
-	if len(cfg.Skills) > 0 {
-		skillCtx := skills.FormatContext(cfg.Skills)
+	if available := offeredSkills(cfg); len(available) > 0 {
+		skillCtx := skills.FormatContext(available)
 		if skillCtx != "" {
 			if systemParts != "" {
 				systemParts += "\n\n"
@@ -193,8 +419,6 @@ func buildMessages(cfg Config) []provider.Message {
 	if systemParts != "" {
 		msgs = append(msgs, provider.Message{Role: "system", Content: systemParts})
 	}
-
-	msgs = append(msgs, provider.Message{Role: "user", Content: cfg.UserPrompt})
 	return msgs
 }
 
@@ -210,18 +434,95 @@ func inferReasoningSummary(text string) string {
 	return ""
 }
 
-func buildToolDefs(discovered []skills.Skill) []provider.ToolDef {
-	tools := []provider.ToolDef{terminalToolDef()}
-	for _, s := range discovered {
-		tools = append(tools, provider.ToolDef{
-			Name:        s.Name,
-			Description: s.Description,
-			Parameters:  `{"type":"object","properties":{}}`,
-		})
+// allowsTool reports whether the agent's tool settings permit name.
+func allowsTool(cfg Config, name string) bool {
+	if cfg.DisableTools {
+		return false
+	}
+	if cfg.Tools == nil {
+		return true
+	}
+	for _, t := range cfg.Tools {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// offeredSkills returns the skills the model may call.
+func offeredSkills(cfg Config) []skills.Skill {
+	var out []skills.Skill
+	for _, s := range cfg.Skills {
+		if allowsTool(cfg, s.Name) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// checkToolAllowlist rejects a tools entry that names neither the terminal
+// nor an available skill, so a typo does not silently drop a tool.
+func checkToolAllowlist(cfg Config) error {
+	for _, name := range cfg.Tools {
+		if name == terminalToolName || name == delegateToolName || name == readResourceToolName {
+			continue
+		}
+		found := false
+		for _, s := range cfg.Skills {
+			if s.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("tools: %q does not match any available tool", name)
+		}
+	}
+	return nil
+}
+
+// buildToolDefs returns the tools offered to the model.
+func buildToolDefs(cfg Config) []provider.ToolDef {
+	var tools []provider.ToolDef
+	if allowsTool(cfg, terminalToolName) {
+		tools = append(tools, terminalToolDef())
+	}
+	available := offeredSkills(cfg)
+	for _, s := range available {
+		tools = append(tools, skillToolDef(s))
+	}
+	if len(available) > 0 && allowsTool(cfg, readResourceToolName) {
+		tools = append(tools, readResourceToolDef(available))
+	}
+	if cfg.Delegate != nil && len(cfg.Delegate.Agents) > 0 && allowsTool(cfg, delegateToolName) {
+		tools = append(tools, delegateToolDef(cfg.Delegate.Agents))
 	}
 	return tools
 }
 
+// checkToolChoice rejects a tool-choice that names a tool not offered to the model.
+func checkToolChoice(choice string, tools []provider.ToolDef) error {
+	switch choice {
+	case "", provider.ToolChoiceAuto, provider.ToolChoiceNone, provider.ToolChoiceRequired:
+		return nil
+	}
+	if offered(tools, choice) {
+		return nil
+	}
+	return fmt.Errorf("tool-choice %q does not match any available tool", choice)
+}
+
+// offered reports whether a tool named name is among tools.
+func offered(tools []provider.ToolDef, name string) bool {
+	for _, t := range tools {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func terminalToolDef() provider.ToolDef {
 	return provider.ToolDef{
 		Name:        terminalToolName,
@@ -239,18 +540,64 @@ func executeToolCall(tc provider.ToolCall, cfg Config) (string, error) {
 		return runTerminalCommand(args.Command, cfg.BaseDir)
 	}
 
-	// Find matching skill.
-	for _, s := range cfg.Skills {
-		if s.Name == tc.Name {
-			// For now, treat the skill's body as the result (instructions).
-			// Full script execution will be wired when skills have scripts/.
-			return fmt.Sprintf("[skill: %s]\n%s", s.Name, s.Body), nil
-		}
+	if tc.Name == readResourceToolName {
+		return readSkillResource(tc.Arguments, cfg)
+	}
+	if s, ok := findSkill(cfg, tc.Name); ok {
+		return runSkill(s, tc.Arguments, cfg)
 	}
 
 	return "", fmt.Errorf("unknown tool: %s", tc.Name)
 }
 
+// delegateToolDef describes the delegate tool, listing the agents it accepts.
+func delegateToolDef(agents []DelegateAgent) provider.ToolDef {
+	var desc strings.Builder
+	desc.WriteString("Hand a focused subtask to a specialist agent, which works on it in its own session and returns its final answer. Give the task everything the agent needs; it does not see this conversation. Agents:")
+	names := make([]string, len(agents))
+	for i, a := range agents {
+		names[i] = a.Name
+		fmt.Fprintf(&desc, "\n- %s", a.Name)
+		if a.Description != "" {
+			fmt.Fprintf(&desc, ": %s", a.Description)
+		}
+	}
+	params, _ := json.Marshal(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"agent": map[string]interface{}{"type": "string", "enum": names, "description": "Name of the agent to delegate to."},
+			"task":  map[string]interface{}{"type": "string", "description": "The subtask, stated completely."},
+		},
+		"required": []string{"agent", "task"},
+	})
+	return provider.ToolDef{Name: delegateToolName, Description: desc.String(), Parameters: string(params)}
+}
+
+type delegateArgs struct {
+	Agent string `json:"agent"`
+	Task  string `json:"task"`
+}
+
+// parseDelegateArgs decodes delegate arguments and checks the agent is one
+// that was offered.
+func parseDelegateArgs(raw string, d *Delegate) (delegateArgs, error) {
+	var args delegateArgs
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &args); err != nil {
+		return delegateArgs{}, fmt.Errorf("invalid delegate arguments: %w", err)
+	}
+	args.Agent = strings.TrimSpace(args.Agent)
+	args.Task = strings.TrimSpace(args.Task)
+	if args.Agent == "" || args.Task == "" {
+		return delegateArgs{}, errors.New("delegate tool requires agent and task")
+	}
+	for _, a := range d.Agents {
+		if a.Name == args.Agent {
+			return args, nil
+		}
+	}
+	return delegateArgs{}, fmt.Errorf("delegate: unknown agent %q", args.Agent)
+}
+
 type terminalArgs struct {
 	Command string `json:"command"`
 }
@@ -278,6 +625,23 @@ func parseTerminalArgs(raw string) (terminalArgs, error) {
 	return args, nil
 }
 
+// toolExitCode maps a tool execution error to an exit status: 0 on success,
+// the process exit code when a command ran and failed, -1 otherwise.
+func toolExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	var skillErr *skillExitError
+	if errors.As(err, &skillErr) {
+		return skillErr.code
+	}
+	return -1
+}
+
 func runTerminalCommand(command, workDir string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
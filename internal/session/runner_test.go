@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -16,6 +18,7 @@ import (
 
 	"run-ai/internal/output"
 	"run-ai/internal/provider"
+	"run-ai/internal/schema"
 	"run-ai/internal/skills"
 )
 
@@ -234,6 +237,140 @@ func TestRunSilentMode(t *testing.T) {
 	}
 }
 
+// --- JSON output test ---
+
+func TestRunJSONOutputReportsToolStatus(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh exit codes")
+	}
+	calls := 0
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if calls == 1 {
+			fmt.Fprintln(w, `data: {"type":"response.function_call_arguments.done","item":{"call_id":"c1","name":"terminal","arguments":"{\"command\":\"exit 3\"}"}}`)
+		} else {
+			fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"it failed"}`)
+		}
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, JSON: true, Now: nowFunc()})
+	err := Run(context.Background(), Config{Provider: p, Sink: sink, UserPrompt: "run it"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	sink.Close()
+
+	var kinds []string
+	var exitCode *int
+	var final string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var ev output.JSONEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		kinds = append(kinds, string(ev.Kind))
+		if ev.Kind == output.EventERR && ev.Tool != nil {
+			exitCode = ev.Tool.ExitCode
+		}
+		if ev.Kind == output.EventFinal {
+			final = ev.Text
+		}
+	}
+	if got := strings.Join(kinds, ","); got != "CMD,ERR,AI,FINAL" {
+		t.Errorf("event kinds = %s", got)
+	}
+	if exitCode == nil || *exitCode != 3 {
+		t.Errorf("expected exit code 3, got %v", exitCode)
+	}
+	if final != "it failed" {
+		t.Errorf("final = %q", final)
+	}
+}
+
+// --- Response schema tests ---
+
+// replyServer answers the nth request with replies[n-1] (the last reply
+// repeats) and records each request body.
+func replyServer(t *testing.T, replies ...string) (provider.Provider, *[]string) {
+	t.Helper()
+	var bodies []string
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		reply := replies[min(len(bodies), len(replies))-1]
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"type\":\"response.output_text.delta\",\"delta\":%q}\n", reply)
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+	return p, &bodies
+}
+
+func TestRunResponseSchemaRetries(t *testing.T) {
+	s, _ := schema.Parse([]byte(`{"type":"object","required":["title"]}`))
+	p, bodies := replyServer(t, `{"name":"x"}`, "```json\n{\"title\":\"ok\"}\n```")
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Silent: true, Now: nowFunc()})
+	messages, err := RunConversation(context.Background(), Config{
+		Provider:       p,
+		Sink:           sink,
+		UserPrompt:     "extract",
+		ResponseSchema: s,
+		SchemaRetries:  1,
+	})
+	sink.Close()
+	if err != nil {
+		t.Fatalf("RunConversation: %v", err)
+	}
+	if len(*bodies) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(*bodies))
+	}
+	if !strings.Contains((*bodies)[0], `"format":{"type":"json_schema"`) {
+		t.Errorf("schema not sent to provider: %s", (*bodies)[0])
+	}
+	if !strings.Contains((*bodies)[1], `missing required property`) {
+		t.Errorf("retry prompt missing validation errors: %s", (*bodies)[1])
+	}
+	if !strings.HasSuffix(buf.String(), "{\"title\":\"ok\"}\n") {
+		t.Errorf("expected unfenced JSON as final output, got %q", buf.String())
+	}
+	if got := messages[len(messages)-1].Role; got != "assistant" {
+		t.Errorf("last message role = %s", got)
+	}
+}
+
+func TestRunResponseSchemaFailsAfterRetries(t *testing.T) {
+	s, _ := schema.Parse([]byte(`{"type":"object"}`))
+	p, bodies := replyServer(t, "not json")
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Silent: true, Now: nowFunc()})
+	err := Run(context.Background(), Config{
+		Provider:       p,
+		Sink:           sink,
+		UserPrompt:     "extract",
+		ResponseSchema: s,
+		SchemaRetries:  2,
+	})
+	sink.Close()
+
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || schemaErr.Attempts != 3 {
+		t.Fatalf("expected SchemaError after 3 attempts, got %v", err)
+	}
+	if len(*bodies) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(*bodies))
+	}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.HasPrefix(line, "[ERR] ") {
+			t.Errorf("unexpected console line in silent mode: %q", line)
+		}
+	}
+}
+
 // --- Provider error test ---
 
 func TestRunProviderError(t *testing.T) {
@@ -294,6 +431,18 @@ func TestBuildMessagesNoSystem(t *testing.T) {
 	}
 }
 
+func TestBuildMessagesWithImages(t *testing.T) {
+	image := provider.ContentPart{Type: provider.PartImage, MIMEType: "image/png", Data: "AA=="}
+	msgs := buildMessages(Config{UserPrompt: "describe", Images: []provider.ContentPart{image}})
+	user := msgs[len(msgs)-1]
+	if user.Content != "describe" || !user.HasImages() || len(user.Parts) != 2 {
+		t.Fatalf("user msg: %+v", user)
+	}
+	if user.Parts[0].Type != provider.PartText || user.Parts[0].Text != "describe" || user.Parts[1] != image {
+		t.Fatalf("parts: %+v", user.Parts)
+	}
+}
+
 func TestExecuteToolCallTerminal(t *testing.T) {
 	cmd := "echo hello"
 	if runtime.GOOS == "windows" {
@@ -334,3 +483,564 @@ func TestExecuteToolCallTerminalWindowsLs(t *testing.T) {
 		t.Fatalf("expected listing to include test-file.txt, got %q", res)
 	}
 }
+
+// --- Chat tests ---
+
+func TestChatKeepsHistoryAcrossTurns(t *testing.T) {
+	var inputs [][]provider.Message
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []provider.Message `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		inputs = append(inputs, req.Input)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"type\":\"response.output_text.delta\",\"delta\":\"answer %d\"}\n", len(inputs))
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	chat := NewChat(Config{Provider: p, Sink: sink, SystemPrompt: "Be brief."})
+
+	if err := chat.Send(context.Background(), "first"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := chat.Send(context.Background(), "second"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(inputs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(inputs))
+	}
+	second := inputs[1]
+	if len(second) != 4 {
+		t.Fatalf("expected system, user, assistant, user in second request, got %+v", second)
+	}
+	if second[1].Content != "first" || second[2].Role != "assistant" || second[2].Content != "answer 1" || second[3].Content != "second" {
+		t.Fatalf("unexpected history: %+v", second)
+	}
+
+	chat.Reset()
+	if h := chat.History(); len(h) != 1 || h[0].Role != "system" {
+		t.Fatalf("expected only system message after reset, got %+v", h)
+	}
+}
+
+func TestChatDiscardsFailedTurn(t *testing.T) {
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	chat := NewChat(Config{Provider: p, Sink: sink})
+
+	if err := chat.Send(context.Background(), "hi"); err == nil {
+		t.Fatal("expected error")
+	}
+	if h := chat.History(); len(h) != 0 {
+		t.Fatalf("expected failed turn to be discarded, got %+v", h)
+	}
+}
+
+// --- Session store tests ---
+
+func TestRecordRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	rec := NewRecord(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	rec.Messages = []provider.Message{
+		{Role: "user", Content: "list files"},
+		{Role: "assistant", ToolCalls: []provider.ToolCall{{ID: "c1", Name: "terminal", Arguments: `{"command":"ls"}`}}},
+		{Role: "tool", Content: "a.txt", ToolCallID: "c1"},
+		{Role: "assistant", Content: "one file"},
+	}
+	if err := SaveRecord(dir, rec); err != nil {
+		t.Fatalf("SaveRecord: %v", err)
+	}
+
+	loaded, err := LoadRecord(dir, rec.ID)
+	if err != nil {
+		t.Fatalf("LoadRecord: %v", err)
+	}
+	if len(loaded.Messages) != 4 || loaded.Messages[1].ToolCalls[0].Name != "terminal" || loaded.Messages[2].ToolCallID != "c1" {
+		t.Fatalf("tool call history not preserved: %+v", loaded.Messages)
+	}
+
+	if err := DeleteRecord(dir, rec.ID); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	if _, err := LoadRecord(dir, rec.ID); err == nil {
+		t.Fatal("expected error loading deleted session")
+	}
+}
+
+func TestListRecordsNewestFirst(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LatestRecord(dir); err != ErrNoSessions {
+		t.Fatalf("expected ErrNoSessions, got %v", err)
+	}
+
+	older := NewRecord(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	newer := NewRecord(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	for _, rec := range []Record{newer, older} {
+		if err := SaveRecord(dir, rec); err != nil {
+			t.Fatalf("SaveRecord: %v", err)
+		}
+	}
+
+	latest, err := LatestRecord(dir)
+	if err != nil {
+		t.Fatalf("LatestRecord: %v", err)
+	}
+	if latest.ID != newer.ID {
+		t.Fatalf("latest = %s, want %s", latest.ID, newer.ID)
+	}
+}
+
+func TestRecordRejectsPathIDs(t *testing.T) {
+	for _, id := range []string{"", "../x", "a/b", `a\b`, ".hidden"} {
+		if _, err := LoadRecord(t.TempDir(), id); err == nil || !strings.Contains(err.Error(), "invalid session id") {
+			t.Errorf("LoadRecord(%q) = %v, want invalid id error", id, err)
+		}
+	}
+}
+
+func TestRunConversationContinuesHistory(t *testing.T) {
+	var received []provider.Message
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []provider.Message `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		received = req.Input
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"again"}`)
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	history := []provider.Message{
+		{Role: "system", Content: "old system"},
+		{Role: "user", Content: "q1"},
+		{Role: "assistant", Content: "a1"},
+	}
+	msgs, err := RunConversation(context.Background(), Config{
+		Provider:     p,
+		Sink:         sink,
+		SystemPrompt: "ignored when continuing",
+		UserPrompt:   "q2",
+		History:      history,
+	})
+	if err != nil {
+		t.Fatalf("RunConversation: %v", err)
+	}
+	if len(received) != 4 || received[0].Content != "old system" || received[3].Content != "q2" {
+		t.Fatalf("unexpected request history: %+v", received)
+	}
+	if len(msgs) != 5 || msgs[4].Content != "again" {
+		t.Fatalf("unexpected returned history: %+v", msgs)
+	}
+}
+
+// --- Generation options tests ---
+
+func TestRunAppliesGenerationOptions(t *testing.T) {
+	var bodies []map[string]interface{}
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(bodies) == 1 {
+			fmt.Fprintln(w, `data: {"type":"response.function_call_arguments.done","item":{"call_id":"c1","name":"terminal","arguments":"{\"command\":\"echo hi\"}"}}`)
+		} else {
+			fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"done"}`)
+		}
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	temp := 0.1
+	err := Run(context.Background(), Config{
+		Provider:   p,
+		Sink:       sink,
+		UserPrompt: "hi",
+		BaseDir:    t.TempDir(),
+		Options:    provider.GenerationOptions{MaxTokens: 64, Temperature: &temp, ToolChoice: provider.ToolChoiceRequired},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(bodies))
+	}
+	if bodies[0]["max_output_tokens"] != float64(64) || bodies[0]["temperature"] != 0.1 || bodies[0]["tool_choice"] != "required" {
+		t.Fatalf("options not applied to first request: %v", bodies[0])
+	}
+	if bodies[1]["tool_choice"] != "auto" {
+		t.Fatalf("forced tool choice should relax after first request, got %v", bodies[1]["tool_choice"])
+	}
+}
+
+func TestRunRejectsUnknownToolChoice(t *testing.T) {
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	err := Run(context.Background(), Config{
+		Sink:       sink,
+		UserPrompt: "hi",
+		Options:    provider.GenerationOptions{ToolChoice: "missing-tool"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not match any available tool") {
+		t.Fatalf("expected tool-choice error, got %v", err)
+	}
+}
+
+func TestBuildToolDefsAllowlist(t *testing.T) {
+	discovered := []skills.Skill{{Name: "lint", Description: "Lints."}, {Name: "deploy", Description: "Deploys."}}
+	names := func(cfg Config) string {
+		var out []string
+		for _, tool := range buildToolDefs(cfg) {
+			out = append(out, tool.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := names(Config{Skills: discovered}); got != "terminal,lint,deploy,read_skill_resource" {
+		t.Errorf("default tools = %s", got)
+	}
+	if got := names(Config{Skills: discovered, Tools: []string{"lint"}}); got != "lint" {
+		t.Errorf("allowlisted tools = %s", got)
+	}
+	if got := names(Config{Skills: discovered, DisableTools: true}); got != "" {
+		t.Errorf("disabled tools = %s", got)
+	}
+
+	msgs := systemMessages(Config{SystemPrompt: "Be brief.", Skills: discovered, Tools: []string{"lint"}})
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Content, "lint") || strings.Contains(msgs[0].Content, "deploy") {
+		t.Fatalf("system prompt should only describe offered skills: %v", msgs)
+	}
+}
+
+func TestRunRefusesToolNotOffered(t *testing.T) {
+	dir := t.TempDir()
+	var bodies []string
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(bodies) == 1 {
+			fmt.Fprintln(w, `data: {"type":"response.function_call_arguments.done","item":{"call_id":"c1","name":"terminal","arguments":"{\"command\":\"touch ran\"}"}}`)
+		} else {
+			fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"ok"}`)
+		}
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	err := Run(context.Background(), Config{Provider: p, Sink: sink, UserPrompt: "hi", BaseDir: dir, DisableTools: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if strings.Contains(bodies[0], `"tools"`) {
+		t.Errorf("no tools should be offered: %s", bodies[0])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); err == nil {
+		t.Fatal("refused tool call was executed")
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[1], `tool \"terminal\" is not available`) {
+		t.Fatalf("expected refusal fed back to the model, got %v", bodies)
+	}
+}
+
+func TestRunRejectsUnknownToolsEntry(t *testing.T) {
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	err := Run(context.Background(), Config{Sink: sink, UserPrompt: "hi", Tools: []string{"terminal", "missing"}})
+	if err == nil || !strings.Contains(err.Error(), `tools: "missing"`) {
+		t.Fatalf("expected tools error, got %v", err)
+	}
+}
+
+func TestRunDelegatesToAgent(t *testing.T) {
+	var bodies []string
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(bodies) == 1 {
+			fmt.Fprintln(w, `data: {"type":"response.function_call_arguments.done","item":{"call_id":"c1","name":"delegate","arguments":"{\"agent\":\"reviewer\",\"task\":\"review main.go\"}"}}`)
+		} else {
+			fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"done"}`)
+		}
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+
+	var gotAgent, gotTask string
+	delegate := &Delegate{
+		Agents: []DelegateAgent{{Name: "reviewer", Description: "Reviews code."}},
+		Run: func(ctx context.Context, agent, task string) (string, error) {
+			gotAgent, gotTask = agent, task
+			return "looks good", nil
+		},
+	}
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	err := Run(context.Background(), Config{Provider: p, Sink: sink, UserPrompt: "review it", Delegate: delegate, Tools: []string{"delegate"}})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if gotAgent != "reviewer" || gotTask != "review main.go" {
+		t.Errorf("delegated %q to %q", gotTask, gotAgent)
+	}
+	if !strings.Contains(bodies[0], `"name":"delegate"`) || !strings.Contains(bodies[0], "Reviews code.") {
+		t.Errorf("delegate tool not offered: %s", bodies[0])
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[1], "looks good") {
+		t.Errorf("expected the delegated answer fed back, got %v", bodies)
+	}
+	if !strings.Contains(buf.String(), "[CMD] delegate to reviewer: review main.go") {
+		t.Errorf("console = %s", buf.String())
+	}
+}
+
+func TestRunDelegateRejectsUnknownAgent(t *testing.T) {
+	var bodies []string
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(bodies) == 1 {
+			fmt.Fprintln(w, `data: {"type":"response.function_call_arguments.done","item":{"call_id":"c1","name":"delegate","arguments":"{\"agent\":\"nobody\",\"task\":\"x\"}"}}`)
+		} else {
+			fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"ok"}`)
+		}
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+
+	delegate := &Delegate{
+		Agents: []DelegateAgent{{Name: "reviewer"}},
+		Run: func(ctx context.Context, agent, task string) (string, error) {
+			t.Fatal("Run called for an unknown agent")
+			return "", nil
+		},
+	}
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, JSON: true, Now: nowFunc()})
+	if err := Run(context.Background(), Config{Provider: p, Sink: sink, UserPrompt: "hi", Delegate: delegate}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	sink.Close()
+	if len(bodies) != 2 || !strings.Contains(bodies[1], `unknown agent \"nobody\"`) {
+		t.Errorf("expected the error fed back to the model, got %v", bodies)
+	}
+	var tool *output.JSONTool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var ev output.JSONEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		if ev.Kind == output.EventERR {
+			tool = ev.Tool
+		}
+	}
+	if tool == nil || tool.Name != "delegate" || !strings.Contains(tool.Arguments, "nobody") {
+		t.Errorf("ERR event tool = %+v", tool)
+	}
+}
+
+func TestRunHonorsMaxIterations(t *testing.T) {
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintln(w, `data: {"type":"response.function_call_arguments.done","item":{"call_id":"c1","name":"terminal","arguments":"{\"command\":\"true\"}"}}`)
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	err := Run(context.Background(), Config{Provider: p, Sink: sink, UserPrompt: "loop", BaseDir: t.TempDir(), MaxIterations: 2})
+	if err == nil || !strings.Contains(err.Error(), "exceeded 2 tool call iterations") {
+		t.Fatalf("expected iteration limit error, got %v", err)
+	}
+}
+
+func writeScriptSkill(t *testing.T, dir string) skills.Skill {
+	t.Helper()
+	skillDir := filepath.Join(dir, "greet")
+	os.MkdirAll(filepath.Join(skillDir, "scripts"), 0o755)
+	os.WriteFile(filepath.Join(skillDir, "scripts", "greet.sh"), []byte("#!/bin/sh\necho \"hello $1\"\necho warned >&2\nexit 4\n"), 0o755)
+	return skills.Skill{Name: "greet", Description: "Greets.", Dir: skillDir}
+}
+
+func TestRunExecutesSkillScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	dir := t.TempDir()
+	skill := writeScriptSkill(t, dir)
+	var bodies []string
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(bodies) == 1 {
+			fmt.Fprintln(w, `data: {"type":"response.function_call_arguments.done","item":{"call_id":"c1","name":"greet","arguments":"{\"script\":\"scripts/greet.sh\",\"args\":[\"world\"]}"}}`)
+		} else {
+			fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"done"}`)
+		}
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, JSON: true, Now: nowFunc()})
+	err := Run(context.Background(), Config{Provider: p, Sink: sink, UserPrompt: "greet", BaseDir: dir, Skills: []skills.Skill{skill}})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !strings.Contains(bodies[0], `scripts/greet.sh`) {
+		t.Errorf("scripts not offered in the tool schema: %s", bodies[0])
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[1], `hello world\n[stderr]\nwarned`) || !strings.Contains(bodies[1], "exit status 4") {
+		t.Errorf("expected script output fed back, got %v", bodies)
+	}
+
+	var cmd string
+	var exitCode *int
+	for _, ev := range decodeEvents(t, buf.String()) {
+		if ev.Kind == output.EventCMD {
+			cmd = ev.Text
+		}
+		if ev.Kind == output.EventERR && ev.Tool != nil {
+			exitCode = ev.Tool.ExitCode
+		}
+	}
+	if cmd != "greet scripts/greet.sh world" {
+		t.Errorf("CMD = %q", cmd)
+	}
+	if exitCode == nil || *exitCode != 4 {
+		t.Errorf("expected exit code 4, got %v", exitCode)
+	}
+}
+
+func TestRunRejectsInvalidSkillArguments(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	dir := t.TempDir()
+	skill := writeScriptSkill(t, dir)
+	var bodies []string
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(bodies) == 1 {
+			fmt.Fprintln(w, `data: {"type":"response.function_call_arguments.done","item":{"call_id":"c1","name":"greet","arguments":"{\"script\":\"../../bin/sh\"}"}}`)
+		} else {
+			fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"ok"}`)
+		}
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	if err := Run(context.Background(), Config{Provider: p, Sink: sink, UserPrompt: "hi", BaseDir: dir, Skills: []skills.Skill{skill}}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[1], "invalid skill arguments") {
+		t.Errorf("expected the validation error fed back, got %v", bodies)
+	}
+	if strings.Contains(buf.String(), "[CMD]") {
+		t.Errorf("invalid call should not run: %s", buf.String())
+	}
+}
+
+func decodeEvents(t *testing.T, out string) []output.JSONEvent {
+	t.Helper()
+	var events []output.JSONEvent
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var ev output.JSONEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestRunPassesSkillParametersOnStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	dir := t.TempDir()
+	skillDir := filepath.Join(dir, "echo-input")
+	os.MkdirAll(filepath.Join(skillDir, "scripts"), 0o755)
+	os.WriteFile(filepath.Join(skillDir, "scripts", "run.sh"), []byte("#!/bin/sh\ncat\n"), 0o755)
+	os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: echo-input\ndescription: Echoes.\nparameters:\n  type: object\n  properties:\n    word: {type: string}\n  required: [word]\n---\n"), 0o644)
+	skill, err := skills.ParseSkillFile(filepath.Join(skillDir, "SKILL.md"), skillDir)
+	if err != nil {
+		t.Fatalf("ParseSkillFile: %v", err)
+	}
+
+	var bodies []string
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(bodies) == 1 {
+			fmt.Fprintln(w, `data: {"type":"response.function_call_arguments.done","item":{"call_id":"c1","name":"echo-input","arguments":"{\"word\":\"hi\"}"}}`)
+		} else {
+			fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"ok"}`)
+		}
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	if err := Run(context.Background(), Config{Provider: p, Sink: sink, UserPrompt: "hi", BaseDir: dir, Skills: []skills.Skill{skill}}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !strings.Contains(bodies[0], `"required":["word"]`) {
+		t.Errorf("declared schema not used as tool parameters: %s", bodies[0])
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[1], `{\"word\":\"hi\"}`) {
+		t.Errorf("expected the arguments echoed from stdin, got %v", bodies)
+	}
+}
+
+func TestRunReadsSkillResource(t *testing.T) {
+	dir := t.TempDir()
+	skillDir := filepath.Join(dir, "guide")
+	os.MkdirAll(filepath.Join(skillDir, "references"), 0o755)
+	os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: guide\ndescription: Guides.\n---\nRead references/style.md.\n"), 0o644)
+	os.WriteFile(filepath.Join(skillDir, "references", "style.md"), []byte("Use tabs."), 0o644)
+	skill := skills.Skill{Name: "guide", Description: "Guides.", Dir: skillDir}
+
+	var bodies []string
+	p := mockProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(bodies) == 1 {
+			fmt.Fprintln(w, `data: {"type":"response.function_call_arguments.done","item":{"call_id":"c1","name":"read_skill_resource","arguments":"{\"skill\":\"guide\",\"path\":\"references/style.md\"}"}}`)
+		} else {
+			fmt.Fprintln(w, `data: {"type":"response.output_text.delta","delta":"ok"}`)
+		}
+		fmt.Fprintln(w, `data: {"type":"response.completed"}`)
+	})
+
+	var buf bytes.Buffer
+	sink, _ := output.NewSink(output.Options{Console: &buf, Now: nowFunc()})
+	if err := Run(context.Background(), Config{Provider: p, Sink: sink, UserPrompt: "hi", BaseDir: dir, Skills: []skills.Skill{skill}}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !strings.Contains(bodies[0], "references/style.md") {
+		t.Errorf("skill files not listed in the system prompt: %s", bodies[0])
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[1], "Use tabs.") {
+		t.Errorf("expected the file fed back, got %v", bodies)
+	}
+	if !strings.Contains(buf.String(), "[CMD] read_skill_resource guide/references/style.md") {
+		t.Errorf("console = %s", buf.String())
+	}
+}
@@ -0,0 +1,149 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"run-ai/internal/provider"
+	"run-ai/internal/skills"
+)
+
+// skillExitError reports a skill script that exited with a non-zero status,
+// so the tool event carries the script's exit code.
+type skillExitError struct {
+	code int
+}
+
+func (e *skillExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// skillToolDef describes a skill as a tool.  A skill with scripts runs one
+// of them; one without returns its instructions.  Declared parameters
+// describe themselves; otherwise the model is pointed at SKILL.md for the
+// scripts' command-line usage.
+func skillToolDef(s skills.Skill) provider.ToolDef {
+	scripts, _ := skills.Scripts(s)
+	desc := s.Description
+	if len(scripts) > 0 && s.Parameters == nil {
+		desc += " Runs one of the skill's scripts with the given arguments; see its SKILL.md for usage."
+	}
+	return provider.ToolDef{Name: s.Name, Description: desc, Parameters: skills.ToolParameters(s, scripts)}
+}
+
+// parseSkillCall validates a skill tool call's arguments.
+func parseSkillCall(s skills.Skill, raw string) (skills.Call, error) {
+	scripts, err := skills.Scripts(s)
+	if err != nil {
+		return skills.Call{}, err
+	}
+	return skills.ParseCall(s, scripts, raw)
+}
+
+// findSkill returns the skill called name.
+func findSkill(cfg Config, name string) (skills.Skill, bool) {
+	for _, s := range cfg.Skills {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return skills.Skill{}, false
+}
+
+// skillCommand is the CMD label for a skill call: the skill, script and
+// arguments as they would be typed, followed by any JSON input.
+func skillCommand(name string, call skills.Call) string {
+	if call.Script == "" {
+		return "skill: " + name
+	}
+	label := strings.Join(append([]string{name, call.Script}, call.Args...), " ")
+	if call.Input != "" {
+		label += " " + call.Input
+	}
+	return label
+}
+
+// runSkill executes a skill tool call.  The result holds the script's
+// stdout, then its stderr under a [stderr] marker; a non-zero exit is
+// returned as a *skillExitError alongside the output.
+func runSkill(s skills.Skill, raw string, cfg Config) (string, error) {
+	call, err := parseSkillCall(s, raw)
+	if err != nil {
+		return "", err
+	}
+	if call.Script == "" {
+		return fmt.Sprintf("[skill: %s]\n%s", s.Name, s.Body), nil
+	}
+	res, err := skills.ExecuteCall(s, call, cfg.BaseDir)
+	if err != nil {
+		return "", err
+	}
+	out := strings.TrimRight(res.Stdout, "\n")
+	if stderr := strings.TrimRight(res.Stderr, "\n"); stderr != "" {
+		if out != "" {
+			out += "\n"
+		}
+		out += "[stderr]\n" + stderr
+	}
+	if res.ExitCode != 0 {
+		return out, &skillExitError{code: res.ExitCode}
+	}
+	return out, nil
+}
+
+// readResourceArgs are the arguments of a read_skill_resource call.
+type readResourceArgs struct {
+	Skill string `json:"skill"`
+	Path  string `json:"path"`
+}
+
+// readResourceToolDef describes the read_skill_resource tool for the
+// offered skills.
+func readResourceToolDef(available []skills.Skill) provider.ToolDef {
+	names := make([]string, len(available))
+	for i, s := range available {
+		names[i] = s.Name
+	}
+	params, _ := json.Marshal(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"skill": map[string]interface{}{"type": "string", "enum": names, "description": "Name of the skill."},
+			"path":  map[string]interface{}{"type": "string", "description": "File to read, relative to the skill directory, e.g. references/api.md. Defaults to SKILL.md."},
+		},
+		"required":             []string{"skill"},
+		"additionalProperties": false,
+	})
+	return provider.ToolDef{
+		Name:        readResourceToolName,
+		Description: "Read a file from a skill's directory: its SKILL.md instructions (the default) or one of the files listed for the skill, such as references/ or assets/. Read a skill's SKILL.md before using it.",
+		Parameters:  string(params),
+	}
+}
+
+// parseReadResourceArgs validates read_skill_resource arguments against the
+// offered skills.
+func parseReadResourceArgs(raw string, cfg Config) (readResourceArgs, skills.Skill, error) {
+	var args readResourceArgs
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &args); err != nil {
+		return args, skills.Skill{}, fmt.Errorf("invalid %s arguments: %w", readResourceToolName, err)
+	}
+	for _, s := range offeredSkills(cfg) {
+		if s.Name == args.Skill {
+			if args.Path == "" {
+				args.Path = "SKILL.md"
+			}
+			return args, s, nil
+		}
+	}
+	return args, skills.Skill{}, fmt.Errorf("%s: unknown skill %q", readResourceToolName, args.Skill)
+}
+
+// readSkillResource executes a read_skill_resource call.
+func readSkillResource(raw string, cfg Config) (string, error) {
+	args, s, err := parseReadResourceArgs(raw, cfg)
+	if err != nil {
+		return "", err
+	}
+	return skills.ReadResource(s, args.Path, 0)
+}
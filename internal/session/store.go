@@ -0,0 +1,155 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"run-ai/internal/provider"
+)
+
+const (
+	raiDirName      = ".rai"
+	sessionsDirName = "sessions"
+)
+
+// ErrNoSessions is returned by LatestRecord when nothing has been saved yet.
+var ErrNoSessions = errors.New("no saved sessions")
+
+// Record is a persisted conversation.  Unlike the human-readable .log written
+// by output.Sink, a Record holds the exact message history (including tool
+// calls and tool results) so it can be replayed to a model with --continue.
+type Record struct {
+	ID       string             `json:"id"`
+	Created  time.Time          `json:"created"`
+	Updated  time.Time          `json:"updated"`
+	Agent    string             `json:"agent,omitempty"`
+	Model    string             `json:"model,omitempty"`
+	Messages []provider.Message `json:"messages"`
+}
+
+// SessionsDir returns the directory holding saved sessions for a base directory.
+func SessionsDir(baseDir string) string {
+	return filepath.Join(baseDir, raiDirName, sessionsDirName)
+}
+
+// NewRecord returns an empty record with a fresh, time-sortable ID.
+func NewRecord(now time.Time) Record {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return Record{
+		ID:      fmt.Sprintf("%s-%s", now.Format("20060102-150405"), hex.EncodeToString(suffix)),
+		Created: now,
+		Updated: now,
+	}
+}
+
+// SaveRecord writes rec to .rai/sessions/<id>.json, replacing any previous copy.
+func SaveRecord(baseDir string, rec Record) error {
+	path, err := recordPath(baseDir, rec.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating sessions directory: %w", err)
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding session: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadRecord reads the session with the given ID.
+func LoadRecord(baseDir, id string) (Record, error) {
+	path, err := recordPath(baseDir, id)
+	if err != nil {
+		return Record{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Record{}, fmt.Errorf("session %q not found", id)
+		}
+		return Record{}, err
+	}
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return Record{}, fmt.Errorf("session %q: %w", id, err)
+	}
+	return rec, nil
+}
+
+// ListRecords returns every saved session, most recently updated first.
+// Unreadable files are skipped so one corrupt session does not hide the rest.
+func ListRecords(baseDir string) ([]Record, error) {
+	entries, err := os.ReadDir(SessionsDir(baseDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading sessions directory: %w", err)
+	}
+
+	var records []Record
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		rec, err := LoadRecord(baseDir, strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Updated.Equal(records[j].Updated) {
+			return records[i].ID > records[j].ID
+		}
+		return records[i].Updated.After(records[j].Updated)
+	})
+	return records, nil
+}
+
+// LatestRecord returns the most recently updated session.
+func LatestRecord(baseDir string) (Record, error) {
+	records, err := ListRecords(baseDir)
+	if err != nil {
+		return Record{}, err
+	}
+	if len(records) == 0 {
+		return Record{}, ErrNoSessions
+	}
+	return records[0], nil
+}
+
+// DeleteRecord removes the session with the given ID.
+func DeleteRecord(baseDir, id string) error {
+	path, err := recordPath(baseDir, id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("session %q not found", id)
+		}
+		return err
+	}
+	return nil
+}
+
+// recordPath maps an ID to its file, rejecting IDs that could escape the
+// sessions directory.
+func recordPath(baseDir, id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid session id %q", id)
+	}
+	return filepath.Join(SessionsDir(baseDir), id+".json"), nil
+}
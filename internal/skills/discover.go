@@ -18,10 +18,13 @@ func SkillsDir(baseDir string) string {
 }
 
 // Discover scans .rai/skills/ for valid skill directories.
-// Each immediate subdirectory that contains a SKILL.md file is treated as a skill.
-// Invalid or unparseable skills are collected as warnings rather than hard errors
-// so that one bad skill doesn't prevent discovery of the rest.
-func Discover(baseDir string) ([]Skill, []string, error) {
+// Each immediate subdirectory that contains a SKILL.md file is treated as a skill
+// and validated against the agentskills.io specification.  Problems are
+// returned as issues rather than hard errors so that one bad skill doesn't
+// prevent discovery of the rest: a skill with an error-severity issue is left
+// out, one with only warnings is still returned.  For a duplicate name the
+// first directory in name order wins.
+func Discover(baseDir string) ([]Skill, []Issue, error) {
 	dir := SkillsDir(baseDir)
 	entries, err := os.ReadDir(dir)
 	if err != nil {
@@ -32,29 +35,56 @@ func Discover(baseDir string) ([]Skill, []string, error) {
 	}
 
 	var skills []Skill
-	var warnings []string
+	var issues []Issue
+	seen := map[string]string{}
 
 	for _, e := range entries {
 		if !e.IsDir() {
 			continue
 		}
-
 		skillDir := filepath.Join(dir, e.Name())
 		skillFile := filepath.Join(skillDir, skillFileName)
-
 		if _, err := os.Stat(skillFile); err != nil {
 			// Directory without SKILL.md — silently skip.
 			continue
 		}
-
 		skill, err := ParseSkillFile(skillFile, skillDir)
 		if err != nil {
-			warnings = append(warnings, fmt.Sprintf("skill %s: %v", e.Name(), err))
+			issues = append(issues, Issue{Skill: e.Name(), Path: skillFile, Severity: SeverityError, Message: err.Error()})
 			continue
 		}
-
+		found := check(skill, e.Name())
+		if first, ok := seen[skill.Name]; ok {
+			found = append(found, Issue{Skill: e.Name(), Path: skillFile, Severity: SeverityError, Message: fmt.Sprintf("name %q is already used by %s", skill.Name, first)})
+		}
+		issues = append(issues, found...)
+		if hasError(found) {
+			continue
+		}
+		seen[skill.Name] = e.Name()
 		skills = append(skills, skill)
 	}
+	return skills, issues, nil
+}
 
-	return skills, warnings, nil
+// Select returns the discovered skills named in names, in discovery order.
+// A name that matches no skill is an error.
+func Select(discovered []Skill, names []string) ([]Skill, error) {
+	want := map[string]bool{}
+	for _, name := range names {
+		want[name] = true
+	}
+	var out []Skill
+	for _, s := range discovered {
+		if want[s.Name] {
+			out = append(out, s)
+			delete(want, s.Name)
+		}
+	}
+	for _, name := range names {
+		if want[name] {
+			return nil, fmt.Errorf("skill %q not found in .rai/skills", name)
+		}
+	}
+	return out, nil
 }
@@ -3,6 +3,8 @@ package skills
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
@@ -11,6 +13,8 @@ import (
 
 const defaultTimeout = 30 * time.Second
 
+const scriptsDirName = "scripts"
+
 // ExecResult holds the output of a skill script execution.
 type ExecResult struct {
 	Stdout   string
@@ -22,6 +26,13 @@ type ExecResult struct {
 // The script is resolved relative to the skill directory. The working directory
 // for execution is workDir (typically the project root).
 func Execute(skill Skill, scriptPath string, args []string, workDir string) (ExecResult, error) {
+	return ExecuteCall(skill, Call{Script: scriptPath, Args: args}, workDir)
+}
+
+// ExecuteCall is Execute for a parsed Call; its Input, if any, is written to
+// the script's stdin.
+func ExecuteCall(skill Skill, call Call, workDir string) (ExecResult, error) {
+	scriptPath := call.Script
 	fullPath := filepath.Join(skill.Dir, scriptPath)
 
 	// Verify the script exists and is within the skill directory.
@@ -33,15 +44,18 @@ func Execute(skill Skill, scriptPath string, args []string, workDir string) (Exe
 	if err != nil {
 		return ExecResult{}, fmt.Errorf("resolving skill directory: %w", err)
 	}
-	if !strings.HasPrefix(absScript, absSkillDir) {
+	if !within(absSkillDir, absScript) {
 		return ExecResult{}, fmt.Errorf("script path %q escapes skill directory", scriptPath)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, absScript, args...)
+	cmd := exec.CommandContext(ctx, absScript, call.Args...)
 	cmd.Dir = workDir
+	if call.Input != "" {
+		cmd.Stdin = strings.NewReader(call.Input)
+	}
 
 	var stdout, stderr strings.Builder
 	cmd.Stdout = &stdout
@@ -63,3 +77,32 @@ func Execute(skill Skill, scriptPath string, args []string, workDir string) (Exe
 
 	return result, nil
 }
+
+// Scripts lists the files under the skill's scripts/ directory as
+// slash-separated paths relative to the skill directory (e.g.
+// "scripts/run.sh"), sorted.  A skill without scripts/ has none.
+func Scripts(skill Skill) ([]string, error) {
+	root := filepath.Join(skill.Dir, scriptsDirName)
+	var scripts []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(skill.Dir, path)
+		if err != nil {
+			return err
+		}
+		scripts = append(scripts, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing scripts: %w", err)
+	}
+	return scripts, nil
+}
@@ -0,0 +1,253 @@
+package skills
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"run-ai/internal/schema"
+)
+
+// schemaFileName is the sibling file that declares a skill's inputs when
+// SKILL.md has no parameters key.
+const schemaFileName = "schema.json"
+
+// Call is a validated skill invocation: the script to run, its command-line
+// arguments and, for skills with declared parameters, the arguments object
+// as JSON for the script's stdin.
+type Call struct {
+	Script string
+	Args   []string
+	Input  string
+}
+
+// loadParameters resolves the parameters frontmatter value (an inline
+// mapping or a path relative to the skill directory), falling back to a
+// sibling schema.json.  It returns nil when the skill declares no inputs.
+func loadParameters(value interface{}, dir string) (*schema.Schema, error) {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		path := filepath.Join(dir, schemaFileName)
+		raw, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = raw
+	case string:
+		path, err := schemaFilePath(dir, strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("parameters: %w", err)
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("parameters: %w", err)
+		}
+		data = raw
+	case map[string]interface{}:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters: %w", err)
+		}
+		data = raw
+	default:
+		return nil, errors.New("parameters must be a schema file path or a mapping")
+	}
+
+	s, err := schema.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parameters: %w", err)
+	}
+	root, _ := schemaObject(s)
+	if root == nil || root["type"] != "object" {
+		return nil, errors.New(`parameters: schema must have "type": "object"`)
+	}
+	return s, nil
+}
+
+// schemaFilePath resolves the parameters file path, which must stay inside
+// the skill directory, with symlinks resolved, like read_skill_resource paths.
+func schemaFilePath(dir, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("%s: path must be relative to the skill directory", path)
+	}
+	if !within(dir, filepath.Join(dir, filepath.FromSlash(path))) {
+		return "", fmt.Errorf("%s is outside the skill directory", path)
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	full, err := filepath.EvalSymlinks(filepath.Join(realDir, filepath.FromSlash(path)))
+	if err != nil {
+		return "", err
+	}
+	if !within(realDir, full) {
+		return "", fmt.Errorf("%s is outside the skill directory", path)
+	}
+	return full, nil
+}
+
+// schemaObject decodes a schema's root object.
+func schemaObject(s *schema.Schema) (map[string]interface{}, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(s.Raw(), &root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// ToolParameters returns the JSON Schema for the skill's tool-call
+// arguments.  A skill with declared parameters takes them as-is, plus a
+// required script property when it has several scripts to choose from.
+// Otherwise a skill with scripts takes a script and its command-line args,
+// and one without takes nothing.
+func ToolParameters(s Skill, scripts []string) string {
+	if s.Parameters != nil {
+		if len(scripts) < 2 {
+			return string(s.Parameters.Raw())
+		}
+		root, err := schemaObject(s.Parameters)
+		if err != nil {
+			return string(s.Parameters.Raw())
+		}
+		props, _ := root["properties"].(map[string]interface{})
+		if props == nil {
+			props = map[string]interface{}{}
+		}
+		props["script"] = scriptProperty(scripts)
+		root["properties"] = props
+		required, _ := root["required"].([]interface{})
+		root["required"] = append(required, "script")
+		data, _ := json.Marshal(root)
+		return string(data)
+	}
+	if len(scripts) == 0 {
+		return `{"type":"object","properties":{}}`
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"script": scriptProperty(scripts),
+			"args":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Command-line arguments for the script."},
+		},
+		"required":             []string{"script"},
+		"additionalProperties": false,
+	})
+	return string(data)
+}
+
+func scriptProperty(scripts []string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "enum": scripts, "description": "Script to run, relative to the skill directory."}
+}
+
+// ParseCall validates raw tool-call arguments against ToolParameters and
+// turns them into a Call.  With declared parameters the arguments object
+// (minus any script property) becomes the script's stdin and a single
+// script is chosen implicitly.  Call.Script is empty for a skill without
+// scripts.
+func ParseCall(s Skill, scripts []string, raw string) (Call, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		raw = "{}"
+	}
+	sch, err := schema.Parse([]byte(ToolParameters(s, scripts)))
+	if err != nil {
+		return Call{}, err
+	}
+	if err := sch.ValidateJSON(raw); err != nil {
+		return Call{}, fmt.Errorf("invalid skill arguments: %w", err)
+	}
+
+	if s.Parameters == nil {
+		var args struct {
+			Script string   `json:"script"`
+			Args   []string `json:"args"`
+		}
+		if err := json.Unmarshal([]byte(raw), &args); err != nil {
+			return Call{}, fmt.Errorf("invalid skill arguments: %w", err)
+		}
+		return Call{Script: args.Script, Args: args.Args}, nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return Call{}, fmt.Errorf("invalid skill arguments: %w", err)
+	}
+	var call Call
+	switch len(scripts) {
+	case 0:
+	case 1:
+		call.Script = scripts[0]
+	default:
+		call.Script, _ = values["script"].(string)
+		delete(values, "script")
+	}
+	input, err := json.Marshal(values)
+	if err != nil {
+		return Call{}, err
+	}
+	call.Input = string(input)
+	return call, nil
+}
+
+// ParameterLines describes the skill's tool parameters, one per line, as
+// "name (type, required): description", sorted by name.
+func ParameterLines(s Skill, scripts []string) []string {
+	sch, err := schema.Parse([]byte(ToolParameters(s, scripts)))
+	if err != nil {
+		return nil
+	}
+	root, err := schemaObject(sch)
+	if err != nil {
+		return nil
+	}
+	props, _ := root["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	if list, ok := root["required"].([]interface{}); ok {
+		for _, r := range list {
+			if name, ok := r.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		prop, _ := props[name].(map[string]interface{})
+		var attrs []string
+		if t := fmt.Sprint(prop["type"]); prop["type"] != nil {
+			attrs = append(attrs, t)
+		}
+		if required[name] {
+			attrs = append(attrs, "required")
+		}
+		line := name
+		if len(attrs) > 0 {
+			line += " (" + strings.Join(attrs, ", ") + ")"
+		}
+		if enum, ok := prop["enum"].([]interface{}); ok {
+			values := make([]string, len(enum))
+			for i, v := range enum {
+				values[i] = fmt.Sprint(v)
+			}
+			line += ": one of " + strings.Join(values, ", ")
+		} else if desc, ok := prop["description"].(string); ok && desc != "" {
+			line += ": " + desc
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,108 @@
+package skills
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultResourceMaxBytes caps how much of a skill file ReadResource returns.
+const DefaultResourceMaxBytes = 256 << 10
+
+// maxListedResources bounds the file listing in FormatContext so a skill
+// with a large assets/ tree cannot flood the system prompt.
+const maxListedResources = 50
+
+// Resources lists the files in the skill directory as slash-separated paths
+// relative to it (SKILL.md, references/..., assets/..., scripts/...), in
+// lexical order.  Hidden files and directories are skipped.
+func Resources(skill Skill) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(skill.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != skill.Dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(skill.Dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing skill files: %w", err)
+	}
+	return files, nil
+}
+
+// ReadResource returns the text of a file in the skill directory; an empty
+// path reads SKILL.md.  The path may not leave the skill directory, also
+// through symlinks, and files over maxBytes (0 means
+// DefaultResourceMaxBytes) or that are not UTF-8 text are refused.
+func ReadResource(skill Skill, path string, maxBytes int64) (string, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultResourceMaxBytes
+	}
+	path = strings.TrimSpace(path)
+	if path == "" {
+		path = skillFileName
+	}
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("%s: path must be relative to the skill directory", path)
+	}
+	// Check the path as written, then again with symlinks resolved.
+	if !within(skill.Dir, filepath.Join(skill.Dir, filepath.FromSlash(path))) {
+		return "", fmt.Errorf("%s is outside the skill directory", path)
+	}
+	dir, err := filepath.EvalSymlinks(skill.Dir)
+	if err != nil {
+		return "", err
+	}
+	full, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.FromSlash(path)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%s: no such file in skill %s", path, skill.Name)
+		}
+		return "", err
+	}
+	if !within(dir, full) {
+		return "", fmt.Errorf("%s is outside the skill directory", path)
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > maxBytes {
+		return "", fmt.Errorf("%s is %d bytes, over the %d byte limit", path, info.Size(), maxBytes)
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", fmt.Errorf("%s is not a text file", path)
+	}
+	return string(data), nil
+}
+
+// within reports whether path lies inside dir.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
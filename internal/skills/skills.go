@@ -16,6 +16,8 @@ import (
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"run-ai/internal/schema"
 )
 
 // Skill holds parsed metadata and instructions from a single SKILL.md file.
@@ -24,6 +26,22 @@ type Skill struct {
 	Description string // required; what the skill does and when to use it
 	Dir         string // absolute path to the skill directory
 	Body        string // markdown body (activation instructions)
+
+	// Optional agentskills.io fields.  AllowedTools is the space-separated
+	// allowed-tools value split into tool names.
+	License       string
+	Compatibility string
+	Metadata      map[string]string
+	AllowedTools  []string
+
+	// Parameters is the JSON Schema for the skill's inputs, from the
+	// parameters frontmatter key or a sibling schema.json; nil when the
+	// skill declares none.
+	Parameters *schema.Schema
+
+	// Warnings lists non-fatal problems found while parsing, such as
+	// unknown frontmatter fields.
+	Warnings []string
 }
 
 // ParseSkillFile reads and parses a SKILL.md file at the given path.
@@ -59,27 +77,61 @@ func parseSkillContent(content, dir string) (Skill, error) {
 	body := strings.Join(lines[end+1:], "\n")
 	body = strings.TrimPrefix(body, "\n")
 
-	var fm struct {
-		Name        string `yaml:"name"`
-		Description string `yaml:"description"`
-	}
+	var fm map[string]interface{}
 	if err := yaml.Unmarshal([]byte(yamlBlock), &fm); err != nil {
 		return Skill{}, fmt.Errorf("invalid SKILL.md frontmatter: %w", err)
 	}
 
-	if fm.Name == "" {
+	skill := Skill{Dir: dir, Body: body}
+	warn := func(format string, args ...interface{}) {
+		skill.Warnings = append(skill.Warnings, fmt.Sprintf(format, args...))
+	}
+	var err error
+	if skill.Name, err = stringField(fm, "name", warn); err != nil {
+		return Skill{}, err
+	}
+	if skill.Name == "" {
 		return Skill{}, errors.New("SKILL.md missing required 'name' field")
 	}
-	if fm.Description == "" {
+	if skill.Description, err = stringField(fm, "description", warn); err != nil {
+		return Skill{}, err
+	}
+	if skill.Description == "" {
 		return Skill{}, errors.New("SKILL.md missing required 'description' field")
 	}
 
-	return Skill{
-		Name:        fm.Name,
-		Description: fm.Description,
-		Dir:         dir,
-		Body:        body,
-	}, nil
+	// The optional fields were ignored before rai understood them, so a
+	// malformed one is a warning and the field is left empty.
+	if skill.License, err = stringField(fm, "license", warn); err != nil {
+		warn("%v; ignored", err)
+	}
+	if skill.Compatibility, err = stringField(fm, "compatibility", warn); err != nil {
+		warn("%v; ignored", err)
+	}
+	var raw struct {
+		Metadata yaml.Node `yaml:"metadata"`
+	}
+	if err := yaml.Unmarshal([]byte(yamlBlock), &raw); err == nil {
+		skill.Metadata = metadataField(&raw.Metadata, warn)
+	}
+	if skill.AllowedTools, err = allowedToolsField(fm["allowed-tools"]); err != nil {
+		warn("%v; ignored", err)
+	}
+	if skill.Parameters, err = loadParameters(fm["parameters"], dir); err != nil {
+		return Skill{}, err
+	}
+
+	var unknown []string
+	for key := range fm {
+		if !frontmatterKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		skill.Warnings = append(skill.Warnings, fmt.Sprintf("unknown SKILL.md field: %s", key))
+	}
+	return skill, nil
 }
 
 // FormatContext builds XML describing available skills for injection into
@@ -101,6 +153,20 @@ func FormatContext(skills []Skill) string {
 		b.WriteString(fmt.Sprintf("    <name>%s</name>\n", s.Name))
 		b.WriteString(fmt.Sprintf("    <description>%s</description>\n", s.Description))
 		b.WriteString(fmt.Sprintf("    <location>%s/SKILL.md</location>\n", s.Dir))
+		if files, err := Resources(s); err == nil && len(files) > 0 {
+			b.WriteString("    <files>")
+			for i, f := range files {
+				if i == maxListedResources {
+					b.WriteString(fmt.Sprintf(", ... (%d more)", len(files)-i))
+					break
+				}
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(f)
+			}
+			b.WriteString("</files>\n")
+		}
 		b.WriteString("  </skill>\n")
 	}
 	b.WriteString("</available_skills>")
@@ -123,6 +189,64 @@ func FormatList(skills []Skill) string {
 			b.WriteString("\n")
 		}
 		b.WriteString(fmt.Sprintf("%s\n  %s\n  %s", s.Name, s.Description, s.Dir))
+		scripts, _ := Scripts(s)
+		if params := ParameterLines(s, scripts); len(params) > 0 {
+			b.WriteString("\n  parameters:")
+			for _, p := range params {
+				b.WriteString("\n    " + p)
+			}
+		}
+	}
+	return b.String()
+}
+
+// FormatShow returns the details of one skill for `rai skills show`: its
+// metadata, parameters, scripts and other files, then the SKILL.md body.
+func FormatShow(s Skill) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "name: %s\n", s.Name)
+	fmt.Fprintf(&b, "description: %s\n", s.Description)
+	fmt.Fprintf(&b, "path: %s\n", s.Dir)
+	if s.License != "" {
+		fmt.Fprintf(&b, "license: %s\n", s.License)
+	}
+	if s.Compatibility != "" {
+		fmt.Fprintf(&b, "compatibility: %s\n", s.Compatibility)
+	}
+	keys := make([]string, 0, len(s.Metadata))
+	for key := range s.Metadata {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "metadata %s: %s\n", key, s.Metadata[key])
+	}
+	if len(s.AllowedTools) > 0 {
+		fmt.Fprintf(&b, "allowed-tools: %s\n", strings.Join(s.AllowedTools, " "))
+	}
+
+	scripts, _ := Scripts(s)
+	writeSection := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%s:\n", title)
+		for _, line := range lines {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	writeSection("parameters", ParameterLines(s, scripts))
+	writeSection("scripts", scripts)
+	files, _ := Resources(s)
+	var resources []string
+	for _, f := range files {
+		if f != skillFileName && !strings.HasPrefix(f, scriptsDirName+"/") {
+			resources = append(resources, f)
+		}
+	}
+	writeSection("resources", resources)
+
+	b.WriteString("\n")
+	b.WriteString(strings.TrimRight(s.Body, "\n"))
 	return b.String()
 }
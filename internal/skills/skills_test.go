@@ -189,13 +189,27 @@ func TestDiscoverInvalidSkillProducesWarning(t *testing.T) {
 	if len(warnings) != 1 {
 		t.Fatalf("expected 1 warning, got %d: %v", len(warnings), warnings)
 	}
-	if !strings.Contains(warnings[0], "bad-skill") {
+	if !strings.Contains(warnings[0].String(), "bad-skill") {
 		t.Fatalf("warning should mention skill name: %s", warnings[0])
 	}
 }
 
 // --- FormatContext tests ---
 
+func TestSelect(t *testing.T) {
+	discovered := []Skill{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	got, err := Select(discovered, []string{"c", "a"})
+	if err != nil || len(got) != 2 || got[0].Name != "a" || got[1].Name != "c" {
+		t.Fatalf("Select = %v, %v", got, err)
+	}
+	if got, err := Select(discovered, []string{}); err != nil || len(got) != 0 {
+		t.Fatalf("empty Select = %v, %v", got, err)
+	}
+	if _, err := Select(discovered, []string{"a", "missing"}); err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Fatalf("expected error for unknown skill, got %v", err)
+	}
+}
+
 func TestFormatContextEmpty(t *testing.T) {
 	result := FormatContext(nil)
 	if result != "" {
@@ -292,3 +306,266 @@ func TestExecuteScript(t *testing.T) {
 		t.Fatalf("expected exit code 0, got %d", result.ExitCode)
 	}
 }
+
+func TestScripts(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "scripts", "lib"), 0o755)
+	os.WriteFile(filepath.Join(dir, "scripts", "run.sh"), []byte("#!/bin/sh\n"), 0o755)
+	os.WriteFile(filepath.Join(dir, "scripts", "lib", "util.py"), []byte(""), 0o644)
+	os.WriteFile(filepath.Join(dir, "scripts", ".hidden"), []byte(""), 0o644)
+
+	scripts, err := Scripts(Skill{Name: "s", Dir: dir})
+	if err != nil {
+		t.Fatalf("Scripts: %v", err)
+	}
+	if got := strings.Join(scripts, ","); got != "scripts/lib/util.py,scripts/run.sh" {
+		t.Errorf("scripts = %s", got)
+	}
+
+	if scripts, err := Scripts(Skill{Name: "none", Dir: t.TempDir()}); err != nil || len(scripts) != 0 {
+		t.Errorf("skill without scripts/ = %v, %v", scripts, err)
+	}
+}
+
+func TestExecuteRejectsSiblingDirectory(t *testing.T) {
+	_, err := Execute(Skill{Name: "test", Dir: "/tmp/skill"}, "../skill2/run.sh", nil, "/tmp")
+	if err == nil || !strings.Contains(err.Error(), "escapes skill directory") {
+		t.Fatalf("expected path escape error, got %v", err)
+	}
+}
+
+// --- Parameter schema tests ---
+
+func TestParseSkillParameters(t *testing.T) {
+	dir := t.TempDir()
+	content := "---\nname: read\ndescription: Reads.\nparameters:\n  type: object\n  properties:\n    path: {type: string, description: File to read.}\n  required: [path]\n---\nBody.\n"
+	skill, err := parseSkillContent(content, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if skill.Parameters == nil || !strings.Contains(string(skill.Parameters.Raw()), `"required":["path"]`) {
+		t.Fatalf("parameters = %v", skill.Parameters)
+	}
+
+	os.WriteFile(filepath.Join(dir, "schema.json"), []byte(`{"type":"object","properties":{"n":{"type":"integer"}}}`), 0o644)
+	skill, err = parseSkillContent("---\nname: count\ndescription: Counts.\n---\n", dir)
+	if err != nil || skill.Parameters == nil || !strings.Contains(string(skill.Parameters.Raw()), `"n"`) {
+		t.Fatalf("schema.json not loaded: %v, %v", skill.Parameters, err)
+	}
+
+	_, err = parseSkillContent("---\nname: bad\ndescription: Bad.\nparameters:\n  type: string\n---\n", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), `"type": "object"`) {
+		t.Fatalf("expected object schema error, got %v", err)
+	}
+
+	// A schema file path must stay inside the skill directory.
+	skillDir := filepath.Join(dir, "skill")
+	os.MkdirAll(filepath.Join(skillDir, "schemas"), 0o755)
+	os.Rename(filepath.Join(dir, "schema.json"), filepath.Join(skillDir, "schemas", "in.json"))
+	os.WriteFile(filepath.Join(dir, "outside.json"), []byte(`{"type":"object"}`), 0o644)
+	if skill, err = parseSkillContent("---\nname: in\ndescription: d\nparameters: schemas/in.json\n---\n", skillDir); err != nil || skill.Parameters == nil {
+		t.Fatalf("schema file not loaded: %v, %v", skill.Parameters, err)
+	}
+	for _, path := range []string{"../outside.json", filepath.Join(dir, "outside.json")} {
+		_, err = parseSkillContent("---\nname: out\ndescription: d\nparameters: "+path+"\n---\n", skillDir)
+		if err == nil || !strings.Contains(err.Error(), "skill directory") {
+			t.Errorf("%s: expected confinement error, got %v", path, err)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		os.Symlink(filepath.Join(dir, "outside.json"), filepath.Join(skillDir, "link.json"))
+		_, err = parseSkillContent("---\nname: out\ndescription: d\nparameters: link.json\n---\n", skillDir)
+		if err == nil || !strings.Contains(err.Error(), "outside the skill directory") {
+			t.Errorf("symlink: expected confinement error, got %v", err)
+		}
+	}
+}
+
+func TestParseCallWithParameters(t *testing.T) {
+	skill, err := parseSkillContent("---\nname: read\ndescription: Reads.\nparameters:\n  type: object\n  properties:\n    path: {type: string}\n  required: [path]\n  additionalProperties: false\n---\n", t.TempDir())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	call, err := ParseCall(skill, []string{"scripts/read.sh"}, `{"path":"a.txt"}`)
+	if err != nil || call.Script != "scripts/read.sh" || call.Input != `{"path":"a.txt"}` {
+		t.Fatalf("single script call = %+v, %v", call, err)
+	}
+
+	scripts := []string{"scripts/head.sh", "scripts/read.sh"}
+	if !strings.Contains(ToolParameters(skill, scripts), `"script"`) {
+		t.Errorf("several scripts should add a script property: %s", ToolParameters(skill, scripts))
+	}
+	call, err = ParseCall(skill, scripts, `{"script":"scripts/head.sh","path":"a.txt"}`)
+	if err != nil || call.Script != "scripts/head.sh" || call.Input != `{"path":"a.txt"}` {
+		t.Fatalf("script choice = %+v, %v", call, err)
+	}
+
+	if _, err := ParseCall(skill, scripts, `{"path":"a.txt"}`); err == nil || !strings.Contains(err.Error(), `missing required property "script"`) {
+		t.Errorf("expected missing script error, got %v", err)
+	}
+	if _, err := ParseCall(skill, scripts[:1], `{"path":3}`); err == nil || !strings.Contains(err.Error(), "invalid skill arguments") {
+		t.Errorf("expected type error, got %v", err)
+	}
+}
+
+func TestFormatListShowsParameters(t *testing.T) {
+	skill, err := parseSkillContent("---\nname: read\ndescription: Reads.\nparameters:\n  type: object\n  properties:\n    path: {type: string, description: File to read.}\n    lines: {type: integer}\n  required: [path]\n---\n", t.TempDir())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	out := FormatList([]Skill{skill})
+	if !strings.Contains(out, "  parameters:\n    lines (integer)\n    path (string, required): File to read.") {
+		t.Fatalf("unexpected listing:\n%s", out)
+	}
+}
+
+// --- Resource tests ---
+
+func TestResourcesAndReadResource(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "references"), 0o755)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0o755)
+	os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("---\nname: s\ndescription: d\n---\nUse it.\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "references", "api.md"), []byte("# API\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "references", "big.txt"), []byte(strings.Repeat("x", 100)), 0o644)
+	os.WriteFile(filepath.Join(dir, "references", "logo.png"), []byte{0x89, 'P', 'N', 'G', 0}, 0o644)
+	os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref"), 0o644)
+	skill := Skill{Name: "s", Dir: dir}
+
+	files, err := Resources(skill)
+	if err != nil {
+		t.Fatalf("Resources: %v", err)
+	}
+	if got := strings.Join(files, ","); got != "SKILL.md,references/api.md,references/big.txt,references/logo.png" {
+		t.Errorf("files = %s", got)
+	}
+
+	if text, err := ReadResource(skill, "", 0); err != nil || !strings.Contains(text, "Use it.") {
+		t.Errorf("default read = %q, %v", text, err)
+	}
+	if text, err := ReadResource(skill, "references/api.md", 0); err != nil || text != "# API\n" {
+		t.Errorf("reference read = %q, %v", text, err)
+	}
+	for path, want := range map[string]string{
+		"references/big.txt":  "over the 50 byte limit",
+		"references/logo.png": "not a text file",
+		"../outside.md":       "outside the skill directory",
+		"references":          "is a directory",
+		"missing.md":          "no such file",
+	} {
+		if _, err := ReadResource(skill, path, 50); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ReadResource(%s) error = %v, want %q", path, err, want)
+		}
+	}
+	outside := filepath.Join(t.TempDir(), "secret.md")
+	os.WriteFile(outside, []byte("secret"), 0o644)
+	if err := os.Symlink(outside, filepath.Join(dir, "references", "link.md")); err == nil {
+		if _, err := ReadResource(skill, "references/link.md", 0); err == nil || !strings.Contains(err.Error(), "outside the skill directory") {
+			t.Errorf("expected symlink escape error, got %v", err)
+		}
+	}
+}
+
+func TestFormatContextListsFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "assets"), 0o755)
+	os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("x"), 0o644)
+	os.WriteFile(filepath.Join(dir, "assets", "template.txt"), []byte("x"), 0o644)
+
+	xml := FormatContext([]Skill{{Name: "s", Description: "d", Dir: dir}})
+	if !strings.Contains(xml, "<files>SKILL.md, assets/template.txt</files>") {
+		t.Fatalf("missing file listing:\n%s", xml)
+	}
+}
+
+// --- Validation tests ---
+
+func writeSkill(t *testing.T, baseDir, dirName, frontmatter string) string {
+	t.Helper()
+	sdir := filepath.Join(baseDir, ".rai", "skills", dirName)
+	os.MkdirAll(sdir, 0o755)
+	os.WriteFile(filepath.Join(sdir, "SKILL.md"), []byte("---\n"+frontmatter+"---\nBody.\n"), 0o644)
+	return sdir
+}
+
+func TestParseSkillOptionalFields(t *testing.T) {
+	content := "---\nname: pdf\ndescription: PDFs.\nlicense: Apache-2.0\ncompatibility: Needs poppler.\nmetadata:\n  author: docs-team\nallowed-tools: Bash(git:*) Read\n---\n"
+	skill, err := parseSkillContent(content, "/s")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if skill.License != "Apache-2.0" || skill.Compatibility != "Needs poppler." || skill.Metadata["author"] != "docs-team" {
+		t.Errorf("optional fields = %+v", skill)
+	}
+	if strings.Join(skill.AllowedTools, "|") != "Bash(git:*)|Read" {
+		t.Errorf("allowed-tools = %v", skill.AllowedTools)
+	}
+
+	// Malformed optional fields are warnings: skills using them loaded
+	// before the fields were checked.
+	for fm, want := range map[string]string{
+		"license: [a]\n":          "license must be a string; ignored",
+		"license: 2\n":            "license should be a string (quote it)",
+		"metadata:\n  n: 1.0\n":   "metadata.n should be a string (quote it)",
+		"metadata: x\n":           "metadata should be a mapping of strings; ignored",
+		"allowed-tools: {a: b}\n": "allowed-tools must be a space-separated string; ignored",
+	} {
+		skill, err := parseSkillContent("---\nname: s\ndescription: d\n"+fm+"---\n", "/s")
+		if err != nil || len(skill.Warnings) != 1 || skill.Warnings[0] != want {
+			t.Errorf("%q: warnings = %q, %v, want %q", fm, skill.Warnings, err, want)
+		}
+	}
+	skill, _ = parseSkillContent("---\nname: s\ndescription: d\nmetadata:\n  version: 1.0\n---\n", "/s")
+	if skill.Metadata["version"] != "1.0" {
+		t.Errorf("metadata = %v, want the YAML text kept", skill.Metadata)
+	}
+	if _, err := parseSkillContent("---\nname: [s]\ndescription: d\n---\n", "/s"); err == nil || !strings.Contains(err.Error(), "name must be a string") {
+		t.Errorf("name list: error = %v", err)
+	}
+}
+
+func TestDiscoverValidatesSpec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executable bits are not checked on Windows")
+	}
+	dir := t.TempDir()
+	writeSkill(t, dir, "Bad_Name", "name: Bad_Name\ndescription: d\n")
+	writeSkill(t, dir, "long-desc", "name: long-desc\ndescription: "+strings.Repeat("x", 1025)+"\n")
+	writeSkill(t, dir, "renamed", "name: original\ndescription: d\nflavor: x\n")
+	writeSkill(t, dir, "original", "name: original\ndescription: d\n")
+	sdir := writeSkill(t, dir, "scripted", "name: scripted\ndescription: d\n")
+	os.MkdirAll(filepath.Join(sdir, "scripts"), 0o755)
+	os.WriteFile(filepath.Join(sdir, "scripts", "run.sh"), []byte("#!/bin/sh\n"), 0o644)
+
+	discovered, issues, err := Discover(dir)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	var names []string
+	for _, s := range discovered {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "Bad_Name,long-desc,original,scripted" {
+		t.Errorf("loaded skills = %s", got)
+	}
+
+	var got []string
+	for _, i := range issues {
+		got = append(got, i.Skill+" "+i.Severity+" "+i.Message)
+	}
+	want := []string{
+		`Bad_Name warning name "Bad_Name" should be lowercase letters, digits and single hyphens, not starting or ending with a hyphen`,
+		`long-desc warning description is 1025 characters, over the 1024 limit`,
+		`renamed warning name "original" does not match its directory "renamed"`,
+		`renamed warning unknown SKILL.md field: flavor`,
+		`renamed error name "original" is already used by original`,
+		`scripted warning scripts/run.sh is not executable (chmod +x scripts/run.sh)`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if issues[0].Path != filepath.Join(dir, ".rai", "skills", "Bad_Name", "SKILL.md") {
+		t.Errorf("path = %s", issues[0].Path)
+	}
+}
@@ -0,0 +1,172 @@
+package skills
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limits from the agentskills.io specification.
+const (
+	maxNameLength          = 64
+	maxDescriptionLength   = 1024
+	maxCompatibilityLength = 500
+)
+
+// Issue severities.  A skill with an error is not loaded; warnings are
+// reported but the skill is still usable.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is one validation finding for a skill directory.
+type Issue struct {
+	Skill    string `json:"skill"` // skill directory name
+	Path     string `json:"path"`  // file the issue concerns
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// String formats the issue the way Discover has always reported problems:
+// "skill <dir>: <message>".
+func (i Issue) String() string {
+	return fmt.Sprintf("skill %s: %s", i.Skill, i.Message)
+}
+
+// namePattern is the agentskills.io name format: lowercase letters, digits
+// and single hyphens, not at either end.
+var namePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// frontmatterKeys are the SKILL.md fields rai understands: the
+// agentskills.io fields plus parameters.
+var frontmatterKeys = map[string]bool{
+	"name": true, "description": true, "license": true, "compatibility": true,
+	"metadata": true, "allowed-tools": true, "parameters": true,
+}
+
+// stringField returns an optional string field of the frontmatter.  Other
+// scalars are converted with a warning, as they were before the field was
+// checked; a list or mapping is an error.
+func stringField(fm map[string]interface{}, key string, warn func(string, ...interface{})) (string, error) {
+	switch v := fm[key].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return strings.TrimSpace(v), nil
+	case map[string]interface{}, []interface{}:
+		return "", fmt.Errorf("%s must be a string", key)
+	default:
+		warn("%s should be a string (quote it)", key)
+		return fmt.Sprint(v), nil
+	}
+}
+
+// metadataField returns the metadata mapping.  Values should be strings;
+// other scalars keep their YAML text (version: 1.0 gives "1.0") with a
+// warning, and anything else is ignored with a warning.
+func metadataField(node *yaml.Node, warn func(string, ...interface{})) map[string]string {
+	if node.Kind == 0 {
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		warn("metadata should be a mapping of strings; ignored")
+		return nil
+	}
+	out := make(map[string]string, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			warn("metadata.%s should be a string; ignored", key)
+			continue
+		}
+		if value.Tag != "!!str" {
+			warn("metadata.%s should be a string (quote it)", key)
+		}
+		out[key] = value.Value
+	}
+	return out
+}
+
+// allowedToolsField splits allowed-tools, a space-separated string.  A YAML
+// list of strings is accepted too.
+func allowedToolsField(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return strings.Fields(v), nil
+	case []interface{}:
+		tools := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("allowed-tools must be a space-separated string")
+			}
+			tools = append(tools, s)
+		}
+		return tools, nil
+	}
+	return nil, fmt.Errorf("allowed-tools must be a space-separated string")
+}
+
+// check validates a parsed skill against the agentskills.io rules that
+// need more than its frontmatter: the name format and its match with the
+// directory, field lengths and executable scripts.
+func check(s Skill, dirName string) []Issue {
+	skillFile := filepath.Join(s.Dir, skillFileName)
+	var issues []Issue
+	add := func(severity, path, format string, args ...interface{}) {
+		issues = append(issues, Issue{Skill: dirName, Path: path, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Skills that break these rules loaded before rai checked them, so they
+	// are warnings: the skill is still used.
+	switch {
+	case utf8.RuneCountInString(s.Name) > maxNameLength:
+		add(SeverityWarning, skillFile, "name %q is longer than %d characters", s.Name, maxNameLength)
+	case !namePattern.MatchString(s.Name):
+		add(SeverityWarning, skillFile, "name %q should be lowercase letters, digits and single hyphens, not starting or ending with a hyphen", s.Name)
+	case s.Name != dirName:
+		add(SeverityWarning, skillFile, "name %q does not match its directory %q", s.Name, dirName)
+	}
+	if n := utf8.RuneCountInString(s.Description); n > maxDescriptionLength {
+		add(SeverityWarning, skillFile, "description is %d characters, over the %d limit", n, maxDescriptionLength)
+	}
+	if n := utf8.RuneCountInString(s.Compatibility); n > maxCompatibilityLength {
+		add(SeverityWarning, skillFile, "compatibility is %d characters, over the %d limit", n, maxCompatibilityLength)
+	}
+	for _, w := range s.Warnings {
+		add(SeverityWarning, skillFile, "%s", w)
+	}
+
+	scripts, err := Scripts(s)
+	if err != nil {
+		add(SeverityWarning, filepath.Join(s.Dir, scriptsDirName), "%v", err)
+	}
+	if runtime.GOOS != "windows" {
+		for _, script := range scripts {
+			path := filepath.Join(s.Dir, filepath.FromSlash(script))
+			if info, err := os.Stat(path); err == nil && info.Mode()&0o111 == 0 {
+				add(SeverityWarning, path, "%s is not executable (chmod +x %s)", script, script)
+			}
+		}
+	}
+	return issues
+}
+
+// hasError reports whether any issue is an error.
+func hasError(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}